@@ -0,0 +1,18 @@
+//go:build darwin || freebsd || netbsd
+// +build darwin freebsd netbsd
+
+package bsh
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// accessTime returns the last access time of the file, if available, otherwise its ModTime
+func accessTime(fi fs.FileInfo) time.Time {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(st.Atimespec.Unix())
+	}
+	return fi.ModTime()
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !windows
+// +build !linux,!darwin,!freebsd,!netbsd,!windows
+
+package bsh
+
+import (
+	"io/fs"
+	"time"
+)
+
+// accessTime returns the ModTime of the file, as access time isn't supported on this OS
+func accessTime(fi fs.FileInfo) time.Time {
+	return fi.ModTime()
+}
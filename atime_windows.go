@@ -0,0 +1,15 @@
+package bsh
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// accessTime returns the last access time of the file, if available, otherwise its ModTime
+func accessTime(fi fs.FileInfo) time.Time {
+	if data, ok := fi.Sys().(*syscall.Win32FileAttributeData); ok {
+		return time.Unix(0, data.LastAccessTime.Nanoseconds())
+	}
+	return fi.ModTime()
+}
@@ -0,0 +1,93 @@
+package bsh
+
+import (
+	"strings"
+)
+
+// BraceExpand expands bash-style brace patterns into every combination of literal strings,
+// for example "file.{go,md}" becomes ["file.go", "file.md"], and "{a,b}{1,2}" becomes
+// ["a1", "a2", "b1", "b2"]. Groups may be nested, and alternatives may be empty ("a{,b}"
+// becomes ["a", "ab"]). As in bash, braces that don't contain a comma, or that aren't
+// balanced, are left as-is, and a backslash can be used to escape a brace or comma.
+func (b *Bsh) BraceExpand(pattern string) []string {
+	expanded := braceExpand(pattern)
+	for i := range expanded {
+		expanded[i] = unescapeBraces(expanded[i])
+	}
+	return expanded
+}
+
+// braceExpand recursively expands the first brace group in str (leaving escapes in place)
+func braceExpand(str string) []string {
+	for i := 0; i < len(str); i++ {
+		switch str[i] {
+		case '\\':
+			i++
+		case '{':
+			end, commas := findBraceGroup(str, i)
+			if end < 0 || len(commas) == 0 {
+				continue
+			}
+			prefix := str[:i]
+			suffixes := braceExpand(str[end+1:])
+			alts := make([]string, 0, len(commas)+1)
+			start := i + 1
+			for _, comma := range commas {
+				alts = append(alts, str[start:comma])
+				start = comma + 1
+			}
+			alts = append(alts, str[start:end])
+
+			out := make([]string, 0, len(alts)*len(suffixes))
+			for _, alt := range alts {
+				for _, a := range braceExpand(alt) {
+					for _, s := range suffixes {
+						out = append(out, prefix+a+s)
+					}
+				}
+			}
+			return out
+		}
+	}
+	return []string{str}
+}
+
+// findBraceGroup returns the index of the brace that closes the brace at str[open], and the
+// indexes of any commas in the group that aren't inside a nested group.
+// If the group isn't closed, end is -1.
+func findBraceGroup(str string, open int) (end int, commas []int) {
+	depth := 0
+	for i := open; i < len(str); i++ {
+		switch str[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i, commas
+			}
+		case ',':
+			if depth == 1 {
+				commas = append(commas, i)
+			}
+		}
+	}
+	return -1, nil
+}
+
+// unescapeBraces removes the backslashes used to escape braces, commas, and backslashes
+func unescapeBraces(str string) string {
+	if !strings.Contains(str, "\\") {
+		return str
+	}
+	var sb strings.Builder
+	for i := 0; i < len(str); i++ {
+		if str[i] == '\\' && i+1 < len(str) && strings.IndexByte(`{},\`, str[i+1]) >= 0 {
+			i++
+		}
+		sb.WriteByte(str[i])
+	}
+	return sb.String()
+}
@@ -0,0 +1,32 @@
+package bsh
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBraceExpand(t *testing.T) {
+	cases := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"plain.txt", []string{"plain.txt"}},
+		{"file.{go,md,txt}", []string{"file.go", "file.md", "file.txt"}},
+		{"{a,b}{1,2}", []string{"a1", "a2", "b1", "b2"}},
+		{"a{b,c{d,e}}f", []string{"abf", "acdf", "acef"}},
+		{"a{,b}", []string{"a", "ab"}},
+		{"{single}", []string{"{single}"}},
+		{"{x}{1,2}", []string{"{x}1", "{x}2"}},
+		{"unclosed{a,b", []string{"unclosed{a,b"}},
+		{`\{a,b}`, []string{"{a,b}"}},
+		{`{a\,b,c}`, []string{"a,b", "c"}},
+	}
+
+	b := Bsh{}
+	for _, c := range cases {
+		actual := b.BraceExpand(c.pattern)
+		if !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf(`BraceExpand("%s"): expected %q, but got %q`, c.pattern, c.expected, actual)
+		}
+	}
+}
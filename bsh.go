@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"runtime"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 const (
@@ -18,9 +21,18 @@ type Bsh struct {
 	Stdout       io.Writer
 	Stderr       io.Writer
 	DisableColor bool
+	// DryRun causes file writes and appends (including UpdateJSON), copies, moves, links, and
+	// concatenations (Concat and ConcatSep) to echo what they would do, instead of doing it. Other
+	// operations (eg MkdirAll, Remove, Chmod, archives, and running commands) are not affected.
+	DryRun bool
 
 	fnErr       func(error)
 	echoFilters []string
+	// stdin is wrapped in a bufio.Reader that is kept between reads, so no buffered input is lost
+	stdinReader *bufio.Reader
+	stdinSource io.Reader
+	// if AskTimeout gives up waiting for a line, the read it started is finished by the next read
+	pendingLine chan lineResult
 	// defaults to Mage's verbose flag, since this package was original written to be used in Magefiles.
 	// However, if you want to use your own VERBOSE flag here, just call SetVerboseEnvVarName.
 	verboseEnvVar string
@@ -34,6 +46,32 @@ func (b *Bsh) ensureStdin() io.Reader {
 	return b.Stdin
 }
 
+// ensureStdinReader returns a bufio.Reader wrapping ensureStdin(), which is reused until Stdin changes
+func (b *Bsh) ensureStdinReader() *bufio.Reader {
+	in := b.ensureStdin()
+	if b.stdinReader == nil || b.stdinSource != in {
+		b.stdinReader = bufio.NewReader(in)
+		b.stdinSource = in
+	}
+	return b.stdinReader
+}
+
+type lineResult struct {
+	str string
+	err error
+}
+
+// readLine reads from stdin up to and including the next newline, first waiting on any read
+// that was started (but not finished) by AskTimeout.
+func (b *Bsh) readLine() (string, error) {
+	if b.pendingLine != nil {
+		r := <-b.pendingLine
+		b.pendingLine = nil
+		return r.str, r.err
+	}
+	return b.ensureStdinReader().ReadString('\n')
+}
+
 // ensureStdout returns Stdout or os.Stdout (never nil, unless os.Stdout is nil)
 func (b *Bsh) ensureStdout() io.Writer {
 	if b.Stdout == nil {
@@ -57,6 +95,15 @@ func (b *Bsh) SetErrorHandler(fnErr func(error)) {
 	b.fnErr = fnErr
 }
 
+// WithErrorHandler temporarily replaces the error handler with fnErr while block runs, then
+// restores the previous error handler (even if block panics).
+func (b *Bsh) WithErrorHandler(fnErr func(error), block func()) {
+	prev := b.fnErr
+	b.fnErr = fnErr
+	defer func() { b.fnErr = prev }()
+	block()
+}
+
 // Panic is called internally any time there's an unhandled error. It will in turn call any
 // error handler set by SetErrorHandler, or panic() if no error handler was set.
 func (b *Bsh) Panic(err error) {
@@ -67,6 +114,23 @@ func (b *Bsh) Panic(err error) {
 	}
 }
 
+// Try runs fn, and if fn panics with an error (which is what Panic does when no error handler
+// has been set), that error is recovered and returned. Panics with values that are not errors,
+// or with runtime errors (eg a nil pointer dereference, which indicate a bug), are not recovered.
+func (b *Bsh) Try(fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(error)
+			if _, isRuntime := r.(runtime.Error); !ok || isRuntime {
+				panic(r)
+			}
+			err = e
+		}
+	}()
+	fn()
+	return nil
+}
+
 // Must can be used to wrap errors that you want bsh to handle.
 func (b *Bsh) Must(err error) {
 	if err != nil {
@@ -84,6 +148,14 @@ func (b *Bsh) PopEchoFilter() {
 	b.echoFilters = b.echoFilters[:len(b.echoFilters)-1]
 }
 
+// applyEchoFilters replaces every occurrence of each echo filter in str with asterisks
+func (b *Bsh) applyEchoFilters(str string) string {
+	for _, v := range b.echoFilters {
+		str = strings.ReplaceAll(str, v, "******")
+	}
+	return str
+}
+
 // Echo writes to stdout, and ensures the last character written is a newline.
 
 func (b *Bsh) Echo(str string) {
@@ -114,8 +186,40 @@ func (b *Bsh) IsVerbose() bool {
 	if len(b.verboseEnvVar) == 0 {
 		b.verboseEnvVar = mageVerboseEnvVar
 	}
-	v, _ := strconv.ParseBool(os.Getenv(b.verboseEnvVar))
-	return v
+	return b.EnvFlag(b.verboseEnvVar, false)
+}
+
+// DebugState returns a multi-line summary of this instance's settings, to help diagnose
+// unexpected behavior (eg verbose output or colors not showing up). The values of echo filters
+// are never included, only how many there are.
+func (b *Bsh) DebugState() string {
+	envVar := b.verboseEnvVar
+	if len(envVar) == 0 {
+		envVar = mageVerboseEnvVar
+	}
+	errHandler := "default (panic)"
+	if b.fnErr != nil {
+		errHandler = "custom"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Verbose: %v (env var %s=%q)\n", b.EnvFlag(envVar, false), envVar, os.Getenv(envVar))
+	fmt.Fprintf(&sb, "DisableColor: %v (supports color: %v)\n", b.DisableColor, b.SupportsColor())
+	fmt.Fprintf(&sb, "DryRun: %v\n", b.DryRun)
+	fmt.Fprintf(&sb, "Echo filters: %d\n", len(b.echoFilters))
+	fmt.Fprintf(&sb, "Error handler: %s\n", errHandler)
+	fmt.Fprintf(&sb, "Stdin: %s\n", describeStream(b.Stdin, "os.Stdin"))
+	fmt.Fprintf(&sb, "Stdout: %s\n", describeStream(b.Stdout, "os.Stdout"))
+	fmt.Fprintf(&sb, "Stderr: %s\n", describeStream(b.Stderr, "os.Stderr"))
+	return sb.String()
+}
+
+// describeStream returns the type of stream, or that def will be used if stream is nil
+func describeStream(stream interface{}, def string) string {
+	if stream == nil {
+		return "nil (defaults to " + def + ")"
+	}
+	return fmt.Sprintf("%T", stream)
 }
 
 func (b *Bsh) Verbose(str string) {
@@ -140,6 +244,181 @@ func (b *Bsh) Warnf(format string, args ...interface{}) {
 	b.echo(fmt.Sprintf(format, args...), ensureNewline, colorWarn)
 }
 
+// Step echoes label, runs fn, then echoes whether fn succeeded or failed, and how long it took.
+// The error from fn is returned (not panicked), so the caller can decide how to handle it.
+func (b *Bsh) Step(label string, fn func() error) error {
+	b.echo(label, ensureNewline, colorEcho)
+	start := time.Now()
+	err := fn()
+	b.echoResult(label, time.Since(start), err)
+	return err
+}
+
+// echoResult echoes that label succeeded (if err is nil) or failed, and how long it took.
+func (b *Bsh) echoResult(label string, elapsed time.Duration, err error) {
+	elapsed = elapsed.Round(time.Millisecond)
+	pass, fail := "✓", "✗"
+	if !b.isTerminal() {
+		pass, fail = "[ok]", "[FAIL]"
+	}
+	if err != nil {
+		b.echo(fmt.Sprintf("%s %s (%v): %v", fail, label, elapsed, err), ensureNewline, colorFailure)
+	} else {
+		b.echo(fmt.Sprintf("%s %s (%v)", pass, label, elapsed), ensureNewline, colorSuccess)
+	}
+}
+
+// SkipIf runs fn unless cond is true, in which case it echoes that fn is being skipped, and why.
+func (b *Bsh) SkipIf(cond bool, reason string, fn func()) {
+	if cond {
+		b.echo("skipping: "+reason, ensureNewline, colorSkip)
+		return
+	}
+	fn()
+}
+
+// RunIf runs fn only if cond is true.
+func (b *Bsh) RunIf(cond bool, fn func()) {
+	if cond {
+		fn()
+	}
+}
+
+// EchoWrap echoes str after word-wrapping it to fit the width of the terminal.
+// Existing newlines in str are preserved, and words longer than the width are not broken.
+func (b *Bsh) EchoWrap(str string) {
+	b.EchoWrapWidth(str, b.TerminalWidth())
+}
+
+// EchoWrapWidth is EchoWrap, but wraps to width instead of the width of the terminal.
+func (b *Bsh) EchoWrapWidth(str string, width int) {
+	b.echo(wrapText(str, width), ensureNewline, colorEcho)
+}
+
+// wrapText inserts newlines between words so no line is wider than width (unless a single word
+// is wider than width). Each line's leading whitespace is kept, and repeated on the lines it wraps
+// onto. ANSI escape sequences don't count towards the width.
+func wrapText(str string, width int) string {
+	var sb strings.Builder
+	for i, line := range strings.Split(str, "\n") {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		lineLen := 0
+		for _, word := range strings.Fields(line) {
+			wordLen := visibleLen(word)
+			if lineLen > 0 && lineLen+1+wordLen > width {
+				sb.WriteString("\n")
+				lineLen = 0
+			}
+			if lineLen > 0 {
+				sb.WriteString(" ")
+				lineLen++
+			} else {
+				sb.WriteString(indent)
+				lineLen = visibleLen(indent)
+			}
+			sb.WriteString(word)
+			lineLen += wordLen
+		}
+	}
+	return sb.String()
+}
+
+// visibleLen returns the number of runes in str, not counting ANSI escape sequences
+func visibleLen(str string) int {
+	n := 0
+	for i := 0; i < len(str); {
+		if strings.HasPrefix(str[i:], ansiCSI) {
+			// skip parameters until the final byte of the sequence (0x40 to 0x7E)
+			i += len(ansiCSI)
+			for i < len(str) && (str[i] < 0x40 || str[i] > 0x7E) {
+				i++
+			}
+			i++
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(str[i:])
+		i += size
+		n++
+	}
+	return n
+}
+
+// EchoTable echoes each row as "key : value", with the keys padded so the values line up.
+func (b *Bsh) EchoTable(rows [][2]string) {
+	width := 0
+	for _, row := range rows {
+		if n := utf8.RuneCountInString(row[0]); n > width {
+			width = n
+		}
+	}
+	var sb strings.Builder
+	for _, row := range rows {
+		sb.WriteString(padRight(row[0], width))
+		sb.WriteString(" : ")
+		sb.WriteString(row[1])
+		sb.WriteString("\n")
+	}
+	if sb.Len() > 0 {
+		b.echo(sb.String(), ensureNewline, colorEcho)
+	}
+}
+
+// EchoColumns echoes a header row followed by each of the given rows, with every column padded
+// to the width of its widest cell. Rows with fewer cells than headers are padded with blanks.
+func (b *Bsh) EchoColumns(headers []string, rows [][]string) {
+	numCols := len(headers)
+	for _, row := range rows {
+		if len(row) > numCols {
+			numCols = len(row)
+		}
+	}
+	widths := make([]int, numCols)
+	measure := func(row []string) {
+		for i, cell := range row {
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	measure(headers)
+	for _, row := range rows {
+		measure(row)
+	}
+
+	var sb strings.Builder
+	writeRow := func(row []string) {
+		var line strings.Builder
+		for i := 0; i < numCols; i++ {
+			var cell string
+			if i < len(row) {
+				cell = row[i]
+			}
+			if i > 0 {
+				line.WriteString("  ")
+			}
+			line.WriteString(padRight(cell, widths[i]))
+		}
+		sb.WriteString(strings.TrimRight(line.String(), " "))
+		sb.WriteString("\n")
+	}
+	writeRow(headers)
+	for _, row := range rows {
+		writeRow(row)
+	}
+	b.echo(sb.String(), ensureNewline, colorEcho)
+}
+
+// padRight adds spaces to the end of str until it is width runes long
+func padRight(str string, width int) string {
+	if n := utf8.RuneCountInString(str); n < width {
+		return str + strings.Repeat(" ", width-n)
+	}
+	return str
+}
+
 type echoOpt byte
 
 const (
@@ -149,6 +428,9 @@ const (
 	colorVerbose  echoOpt = iota
 	colorAsk      echoOpt = iota
 	colorWarn     echoOpt = iota
+	colorSuccess  echoOpt = iota
+	colorFailure  echoOpt = iota
+	colorSkip     echoOpt = iota
 )
 
 func (b *Bsh) echo(str string, opts ...echoOpt) {
@@ -169,29 +451,51 @@ func (b *Bsh) echo(str string, opts ...echoOpt) {
 			color = ansiBlue
 		case colorWarn:
 			color = ansiYellow
+		case colorSuccess:
+			color = ansiGreen
+		case colorFailure:
+			color = ansiRed
+		case colorSkip:
+			color = ansiDarkGray
 		}
 	}
 
 	if filter {
-		for _, v := range b.echoFilters {
-			str = strings.ReplaceAll(str, v, "******")
-		}
+		str = b.applyEchoFilters(str)
 	}
 
-	if newline && str[len(str)-1] != '\n' {
+	if newline && !strings.HasSuffix(str, "\n") {
 		str += "\n"
 	}
 
-	if !b.DisableColor && len(color) > 0 {
-		_, exists := os.LookupEnv("NO_COLOR")
-		if !exists {
-			str = color + str + ansiReset
-		}
+	if len(color) > 0 && b.SupportsColor() {
+		str = color + str + ansiReset
 	}
 
 	fmt.Fprint(b.ensureStdout(), str)
 }
 
+// SupportsColor returns true if output should include ANSI color codes. It returns false if
+// DisableColor is set, the NO_COLOR env var exists, TERM is "dumb" (or is empty, except on
+// Windows, where TERM is rarely set), or Stdout isn't a terminal.
+func (b *Bsh) SupportsColor() bool {
+	if b.DisableColor {
+		return false
+	}
+	if _, exists := os.LookupEnv("NO_COLOR"); exists {
+		return false
+	}
+	switch os.Getenv("TERM") {
+	case "dumb":
+		return false
+	case "":
+		if runtime.GOOS != "windows" {
+			return false
+		}
+	}
+	return b.isTerminal()
+}
+
 // ScanLine reads from default stdin until a newline is encountered
 
 func (b *Bsh) ScanLine() string {
@@ -203,8 +507,7 @@ func (b *Bsh) ScanLine() string {
 }
 
 func (b *Bsh) ScanLineErr() (string, error) {
-	r := bufio.NewReader(b.ensureStdin())
-	str, err := r.ReadString('\n')
+	str, err := b.readLine()
 	if err != nil {
 		return "", err
 	}
@@ -223,6 +526,99 @@ func (b *Bsh) Askf(format string, args ...interface{}) string {
 	return b.ScanLine()
 }
 
+// AskTimeout echoes msg, then waits up to timeout for a line to be entered on stdin. If no
+// line is entered in time, def is returned instead (and a note saying so is echoed).
+func (b *Bsh) AskTimeout(msg, def string, timeout time.Duration) string {
+	b.echo(msg, colorAsk)
+	ch := b.pendingLine
+	if ch == nil {
+		ch = make(chan lineResult, 1)
+		r := b.ensureStdinReader()
+		go func() {
+			str, err := r.ReadString('\n')
+			ch <- lineResult{str, err}
+		}()
+	}
+	b.pendingLine = nil
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-ch:
+		if r.err != nil {
+			b.Panic(r.err)
+		}
+		return strings.TrimSuffix(r.str, "\n")
+	case <-timer.C:
+		b.pendingLine = ch
+		b.echo(fmt.Sprintf("\n(no answer after %v, using default: %s)", timeout, def), ensureNewline, colorAsk)
+		return def
+	}
+}
+
+// AskMultiline echoes msg, then reads lines from stdin until a line matching terminator is
+// entered (or stdin reaches EOF), and returns the lines read, joined by newlines.
+// If terminator is empty, it defaults to ".".
+func (b *Bsh) AskMultiline(msg, terminator string) string {
+	if len(terminator) == 0 {
+		terminator = "."
+	}
+	b.echo(msg, ensureNewline, colorAsk)
+	b.echo(fmt.Sprintf(`(enter "%s" on its own line to finish)`, terminator), ensureNewline, colorAsk)
+
+	lines := make([]string, 0, 16)
+	for {
+		str, err := b.readLine()
+		if err != nil && err != io.EOF {
+			b.Panic(err)
+			break
+		}
+		line := strings.TrimSuffix(strings.TrimSuffix(str, "\n"), "\r")
+		if line == terminator {
+			break
+		}
+		if err == io.EOF {
+			if len(line) > 0 {
+				lines = append(lines, line)
+			}
+			break
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// maxStdinLineLen is the longest line EachStdinLine can handle
+const maxStdinLineLen = 1024 * 1024
+
+// EachStdinLine calls fn for each line read from stdin (without its line ending), until stdin
+// reaches EOF, or fn returns an error. Any error from fn or from reading stdin is returned.
+// This is useful for running as a filter in a shell pipeline.
+func (b *Bsh) EachStdinLine(fn func(line string) error) error {
+	if b.pendingLine != nil {
+		str, err := b.readLine()
+		if len(str) > 0 {
+			if err := fn(strings.TrimRight(str, "\r\n")); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+
+	scanner := bufio.NewScanner(b.ensureStdinReader())
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStdinLineLen)
+	for scanner.Scan() {
+		if err := fn(scanner.Text()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
 // ansi color helpers
 
 const (
@@ -2,8 +2,13 @@ package bsh
 
 import (
 	"bytes"
+	"fmt"
+	"io"
 	"os"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/magefile/mage/mg"
 )
@@ -128,3 +133,214 @@ func Test_IsVerbose(t *testing.T) {
 		t.Errorf(`expected IsVerbose() to be false after SetVerbose(false)`)
 	}
 }
+
+func Test_EchoTable(t *testing.T) {
+	var b bytes.Buffer
+	sh := Bsh{DisableColor: true, Stdout: &b}
+	sh.EchoTable([][2]string{
+		{"Version", "1.2.3"},
+		{"Commit", "abc123"},
+		{"Go", "1.16"},
+	})
+	actual := b.String()
+	expected := "Version : 1.2.3\nCommit  : abc123\nGo      : 1.16\n"
+	if actual != expected {
+		t.Errorf(`expected: "%s", but got "%s"`, expected, actual)
+	}
+}
+
+func Test_EchoColumns(t *testing.T) {
+	var b bytes.Buffer
+	sh := Bsh{DisableColor: true, Stdout: &b}
+	sh.EchoColumns(
+		[]string{"NAME", "SIZE", "NOTES"},
+		[][]string{
+			{"app.zip", "12345", "release"},
+			{"debug-symbols.zip", "9"},
+		},
+	)
+	actual := b.String()
+	expected := "NAME               SIZE   NOTES\n" +
+		"app.zip            12345  release\n" +
+		"debug-symbols.zip  9\n"
+	if actual != expected {
+		t.Errorf(`expected: "%s", but got "%s"`, expected, actual)
+	}
+}
+
+func Test_AskMultiline(t *testing.T) {
+	var out bytes.Buffer
+	in := strings.NewReader("first line\nsecond line\n.\nafter\n")
+	sh := Bsh{DisableColor: true, Stdin: in, Stdout: &out}
+
+	actual := sh.AskMultiline("Changelog:", "")
+	expected := "first line\nsecond line"
+	if actual != expected {
+		t.Errorf(`expected: "%s", but got "%s"`, expected, actual)
+	}
+
+	actual = sh.ScanLine()
+	expected = "after"
+	if actual != expected {
+		t.Errorf(`expected remaining line "%s", but got "%s"`, expected, actual)
+	}
+}
+
+func Test_EchoWrap(t *testing.T) {
+	var b bytes.Buffer
+	sh := Bsh{DisableColor: true, Stdout: &b}
+	sh.EchoWrapWidth("the quick brown fox jumps over the "+ansiRed+"lazy"+ansiReset+" dog\nunbreakable-long-word ok", 15)
+	actual := b.String()
+	expected := "the quick brown\nfox jumps over\nthe " + ansiRed + "lazy" + ansiReset + " dog\nunbreakable-long-word\nok\n"
+	if actual != expected {
+		t.Errorf(`expected: "%q", but got "%q"`, expected, actual)
+	}
+
+	b.Reset()
+	sh.EchoWrapWidth("list:\n  - one two three\n\n  - four", 12)
+	actual = b.String()
+	expected = "list:\n  - one two\n  three\n\n  - four\n"
+	if actual != expected {
+		t.Errorf(`expected: "%q", but got "%q"`, expected, actual)
+	}
+
+	b.Reset()
+	sh.EchoWrapWidth("", 12)
+	if actual = b.String(); actual != "\n" {
+		t.Errorf(`expected: "%q", but got "%q"`, "\n", actual)
+	}
+}
+
+func Test_WithErrorHandler(t *testing.T) {
+	sh := Bsh{}
+	errs := make([]error, 0, 2)
+	sh.WithErrorHandler(func(err error) { errs = append(errs, err) }, func() {
+		sh.Panic(fmt.Errorf("first"))
+		sh.Panic(fmt.Errorf("second"))
+	})
+	if len(errs) != 2 {
+		t.Errorf("expected 2 errors to be collected, but got %d", len(errs))
+	}
+	if sh.fnErr != nil {
+		t.Errorf("expected the error handler to be restored after WithErrorHandler")
+	}
+}
+
+func Test_Try(t *testing.T) {
+	sh := Bsh{}
+
+	err := sh.Try(func() {
+		sh.Must(nil)
+	})
+	if err != nil {
+		t.Errorf("expected no error from Try, but got %v", err)
+	}
+
+	expected := fmt.Errorf("expected error")
+	err = sh.Try(func() {
+		sh.Panic(expected)
+		t.Errorf("expected Panic to stop execution of the block")
+	})
+	if err != expected {
+		t.Errorf(`expected Try to return "%v", but got "%v"`, expected, err)
+	}
+
+	defer func() {
+		if r := recover(); r != "not an error" {
+			t.Errorf(`expected Try to re-panic with "not an error", but got "%v"`, r)
+		}
+	}()
+	sh.Try(func() {
+		panic("not an error")
+	})
+}
+
+func Test_TryRuntimeError(t *testing.T) {
+	sh := Bsh{}
+	defer func() {
+		if _, ok := recover().(runtime.Error); !ok {
+			t.Errorf("expected Try to re-panic with the runtime error")
+		}
+	}()
+	sh.Try(func() {
+		var m map[string]int
+		m["boom"] = 1
+	})
+}
+
+func Test_AskTimeout(t *testing.T) {
+	var out bytes.Buffer
+	r, w := io.Pipe()
+	sh := Bsh{DisableColor: true, Stdin: r, Stdout: &out}
+
+	actual := sh.AskTimeout("Continue? ", "yes", 10*time.Millisecond)
+	if actual != "yes" {
+		t.Errorf(`expected default "yes" after timeout, but got "%s"`, actual)
+	}
+
+	go w.Write([]byte("no\nlater\n"))
+	actual = sh.AskTimeout("Continue? ", "yes", time.Second)
+	if actual != "no" {
+		t.Errorf(`expected "no", but got "%s"`, actual)
+	}
+	actual = sh.ScanLine()
+	if actual != "later" {
+		t.Errorf(`expected "later", but got "%s"`, actual)
+	}
+}
+
+func Test_DebugState(t *testing.T) {
+	var out strings.Builder
+	b := Bsh{Stdout: &out}
+	b.PushEchoFilter("hunter2")
+	state := b.DebugState()
+	if strings.Contains(state, "hunter2") {
+		t.Errorf("expected echo filter values to be hidden, but got:\n%s", state)
+	}
+	for _, expected := range []string{"Echo filters: 1\n", "Stdout: *strings.Builder\n", "Stdin: nil (defaults to os.Stdin)\n"} {
+		if !strings.Contains(state, expected) {
+			t.Errorf("expected %q in:\n%s", expected, state)
+		}
+	}
+}
+
+func Test_RunWithSpinner(t *testing.T) {
+	var out strings.Builder
+	b := Bsh{Stdout: &out, DisableColor: true}
+
+	if err := b.RunWithSpinner("quiet step", `bash -c "echo hidden"`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "quiet step\n[ok] quiet step (") || strings.Contains(out.String(), "hidden") {
+		t.Errorf("unexpected output on success:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := b.RunWithSpinner("failing step", `bash -c "echo shown; exit 1"`); err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(out.String(), "shown\n[FAIL] failing step (") {
+		t.Errorf("unexpected output on failure:\n%s", out.String())
+	}
+}
+
+func Test_EachStdinLine(t *testing.T) {
+	in := strings.NewReader("one\r\ntwo\nstop\nnever\n")
+	sh := Bsh{Stdin: in}
+
+	var lines []string
+	errStop := fmt.Errorf("stop")
+	err := sh.EachStdinLine(func(line string) error {
+		if line == "stop" {
+			return errStop
+		}
+		lines = append(lines, line)
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("expected error from fn to be returned, but got %v", err)
+	}
+	if strings.Join(lines, ",") != "one,two" {
+		t.Errorf(`expected "one,two", but got "%s"`, strings.Join(lines, ","))
+	}
+}
@@ -0,0 +1,84 @@
+package bsh
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Cached runs fn only if the key or the contents of any of the inputs (files or folders) have
+// changed since the last time fn completed for this key. The fingerprint of each completed run
+// is stored in a small file in the user's cache folder (or the temp folder, if the cache
+// folder is unavailable). Stamps are kept per working folder, and inputs are identified by their
+// absolute paths, so the same key used from different checkouts doesn't share a stamp.
+func (b *Bsh) Cached(key string, inputs []string, fn func()) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		b.Panic(err)
+		return
+	}
+	fingerprint, err := fingerprintInputs(key, inputs)
+	if err != nil {
+		b.Panic(err)
+		return
+	}
+
+	cachePath := filepath.Join(cacheDir(), hashString(cwd+"\x00"+key))
+	if prev, err := os.ReadFile(cachePath); err == nil && string(prev) == fingerprint {
+		b.Verbosef("Cached: %s is up to date, skipping", key)
+		return
+	}
+
+	b.Verbosef("Cached: %s has changed, running", key)
+	fn()
+
+	b.EnsureParentDir(cachePath)
+	b.Write(cachePath, fingerprint)
+}
+
+// fingerprintInputs returns a hex-encoded SHA256 digest of the key, and the absolute paths and
+// contents of inputs
+func fingerprintInputs(key string, inputs []string) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "key %s\x00", key)
+	for _, input := range inputs {
+		abs, err := filepath.Abs(input)
+		if err != nil {
+			return "", err
+		}
+		fi, err := os.Stat(abs)
+		switch {
+		case os.IsNotExist(err):
+			fmt.Fprintf(h, "missing %s\x00", abs)
+		case err != nil:
+			return "", err
+		case fi.IsDir():
+			fmt.Fprintf(h, "dir %s\x00", abs)
+			err = hashDirInto(h, abs)
+		default:
+			fmt.Fprintf(h, "file %s\x00%d\x00", abs, fi.Size())
+			err = copyFileTo(h, abs)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashString(str string) string {
+	h := sha256.New()
+	io.WriteString(h, str)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func cacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "bsh_cache")
+	}
+	return filepath.Join(dir, "bsh")
+}
@@ -0,0 +1,55 @@
+package bsh
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCached(t *testing.T) {
+	ensureLocalFolder(t)
+	// keep the stamps out of the real user cache folder (where supported)
+	cacheHome, err := filepath.Abs("local/cache_test_home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, exists := os.LookupEnv("XDG_CACHE_HOME")
+	os.Setenv("XDG_CACHE_HOME", cacheHome)
+	defer func() {
+		if exists {
+			os.Setenv("XDG_CACHE_HOME", old)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+
+	b := Bsh{}
+	b.InDir("local", func() {
+		b.RemoveAll("cache_test")
+		b.MkdirAll("cache_test/src")
+		b.Write("cache_test/src/a.txt", "alpha")
+		// a new key for each run, in case the stamps end up somewhere that isn't cleaned up
+		key := fmt.Sprintf("cache-test-%d", time.Now().UnixNano())
+
+		runs := 0
+		build := func() { runs++ }
+		b.Cached(key, []string{"cache_test/src"}, build)
+		b.Cached(key, []string{"cache_test/src"}, build)
+		if runs != 1 {
+			t.Errorf("expected a cache hit to skip fn, but fn ran %d time(s)", runs)
+		}
+
+		b.Write("cache_test/src/a.txt", "changed")
+		b.Cached(key, []string{"cache_test/src"}, build)
+		if runs != 2 {
+			t.Errorf("expected a changed input to run fn again, but fn ran %d time(s)", runs)
+		}
+
+		b.Cached(key+"-other", []string{"cache_test/src"}, build)
+		if runs != 3 {
+			t.Errorf("expected a different key to run fn, but fn ran %d time(s)", runs)
+		}
+	})
+}
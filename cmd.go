@@ -1,12 +1,20 @@
 package bsh
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/danbrakeley/commandline"
 )
@@ -18,12 +26,29 @@ import (
 
 type Command struct {
 	raw        string
+	args       []string // if set (via CmdArgs), the name and args to run, instead of parsing raw
 	dir        string
 	env        []string
+	cleanEnv   bool      // if true, env replaces the environment of the current process instead of adding to it
 	in         io.Reader // the stdin to attach to this process
 	out        io.Writer // the stdout to attach to this process
 	err        io.Writer // the stderr to attach to this process
 	exitStatus *int      // exit status code
+	outSet     bool      // true if out was set explicitly via Out or OutErr
+	errSet     bool      // true if err was set explicitly via Err or OutErr
+
+	lineWriters []*lineWriter   // wrapped writers that need flushing after the process exits
+	tail        *ringBuffer     // if set, retains the most recent output
+	rlimitCPU   int             // if > 0, max cpu seconds (unix only)
+	rlimitAS    uint64          // if > 0, max address space in bytes (unix only)
+	redactKeys  []string        // env vars whose values are hidden from logs
+	quietRetry  bool            // if true, output from failed RunRetry attempts is discarded
+	ctx         context.Context // if set, the process is killed when this is done
+	timeout     time.Duration   // if > 0, the process (and its process group) is killed after this long
+
+	// set while a process with a timeout is running
+	timeoutCtx    context.Context
+	cancelTimeout context.CancelFunc
 
 	// copied from Bsh at creation
 	b *Bsh
@@ -45,6 +70,43 @@ func (b *Bsh) Cmdf(format string, args ...interface{}) *Command {
 	return b.Cmd(fmt.Sprintf(format, args...))
 }
 
+// CmdArgs is like Cmd, but instead of parsing a command string, the given name and args are
+// passed to the process exactly as they are. This avoids any surprises from parsing when args are
+// built up programmatically (eg paths that contain spaces or quotes).
+func (b *Bsh) CmdArgs(name string, args ...string) *Command {
+	all := append([]string{name}, args...)
+	c := b.Cmd(quoteArgs(all))
+	c.args = all
+	return c
+}
+
+// quoteArgs returns args as a single command string that parses back into the same args
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quoteArg(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// RunInDir runs the command in dir (without changing the current working directory), and
+// returns its stdout with leading and trailing whitespace trimmed.
+func (b *Bsh) RunInDir(dir, command string) string {
+	var sb strings.Builder
+	b.Cmd(command).Dir(dir).Out(&sb).Run()
+	return strings.TrimSpace(sb.String())
+}
+
+// ParseCommandLine splits a command string into args, using the same bash-like parsing that
+// Cmd uses when running a command.
+func (b *Bsh) ParseCommandLine(s string) []string {
+	args, err := commandline.Parse(s)
+	if err != nil {
+		b.Panic(err)
+	}
+	return args
+}
+
 // Command methods
 
 func (c *Command) StdIn() io.Reader {
@@ -64,19 +126,35 @@ func (c *Command) In(r io.Reader) *Command {
 	return c
 }
 
+// StdinStr uses s as the process's stdin.
+func (c *Command) StdinStr(s string) *Command {
+	c.in = strings.NewReader(s)
+	return c
+}
+
+// StdinBytes uses data as the process's stdin.
+func (c *Command) StdinBytes(data []byte) *Command {
+	c.in = bytes.NewReader(data)
+	return c
+}
+
 func (c *Command) Out(w io.Writer) *Command {
 	c.out = w
+	c.outSet = true
 	return c
 }
 
 func (c *Command) Err(w io.Writer) *Command {
 	c.err = w
+	c.errSet = true
 	return c
 }
 
 func (c *Command) OutErr(w io.Writer) *Command {
 	c.out = w
 	c.err = w
+	c.outSet = true
+	c.errSet = true
 	return c
 }
 
@@ -85,20 +163,130 @@ func (c *Command) ExitStatus(n *int) *Command {
 	return c
 }
 
+// ColorStderr colors each line the process writes to stderr, so that diagnostics stand out
+// from regular output. Has no effect if color is disabled.
+func (c *Command) ColorStderr() *Command {
+	if !c.b.SupportsColor() || c.err == nil {
+		return c
+	}
+	lw := newLineWriter(c.err, func(line string) string {
+		return ansiRed + line + ansiReset
+	})
+	c.lineWriters = append(c.lineWriters, lw)
+	c.err = lw
+	return c
+}
+
+// OutTransform passes each line the process writes to stdout through fn, and writes whatever
+// fn returns in its place. If fn returns an empty string, the line is dropped.
+func (c *Command) OutTransform(fn func(line string) string) *Command {
+	if c.out == nil {
+		return c
+	}
+	lw := newLineWriter(c.out, fn)
+	c.lineWriters = append(c.lineWriters, lw)
+	c.out = lw
+	return c
+}
+
+// TailBuffer retains the last maxBytes of the process's stdout and stderr (in addition to
+// writing them to their usual destinations). The retained output is available via LastOutput,
+// and is included in the error if the command fails in a runner that panics.
+func (c *Command) TailBuffer(maxBytes int) *Command {
+	c.tail = newRingBuffer(maxBytes)
+	return c
+}
+
+// LastOutput returns the output retained by TailBuffer during the most recent run.
+func (c *Command) LastOutput() string {
+	if c.tail == nil {
+		return ""
+	}
+	return c.tail.String()
+}
+
 // ExpandEnv calls os.ExpandEnv on the command string before it is parsed and passed to exec.Cmd.
+// For a command made with CmdArgs, os.ExpandEnv is instead called on each arg.
 func (c *Command) ExpandEnv() *Command {
-	c.raw = os.ExpandEnv(c.raw)
+	if c.args == nil {
+		c.raw = os.ExpandEnv(c.raw)
+		return c
+	}
+	for i, arg := range c.args {
+		c.args[i] = os.ExpandEnv(arg)
+	}
+	c.raw = quoteArgs(c.args)
 	return c
 }
 
 // Env adds environment variables in the form "KEY=VALUE", to be set on exec.Cmd.Env.
+// The process also inherits the environment of the current process (unless CleanEnv is used).
 // Note: these env vars are not seen by ExpandEnv.
 func (c *Command) Env(vars ...string) *Command {
 	c.env = append(c.env, vars...)
 	return c
 }
 
-// Dir sets the working directory
+// CleanEnv replaces the environment of the process with exactly the given vars (in the form
+// "KEY=VALUE"), instead of inheriting the environment of the current process. Any vars set by
+// earlier calls to Env are discarded, but later calls to Env add to the clean environment.
+func (c *Command) CleanEnv(vars ...string) *Command {
+	c.env = append([]string{}, vars...)
+	c.cleanEnv = true
+	return c
+}
+
+// PrependPath adds dir to the front of the PATH env var seen by the process, without
+// changing the PATH of the current process. The command itself is also searched for in dir.
+func (c *Command) PrependPath(dir string) *Command {
+	path, ok := c.envValue("PATH")
+	if !ok && !c.cleanEnv {
+		path = os.Getenv("PATH")
+	}
+	if len(path) > 0 {
+		path = dir + string(os.PathListSeparator) + path
+	} else {
+		path = dir
+	}
+	c.env = append(c.env, "PATH="+path)
+	return c
+}
+
+// RedactEnv hides the values of the given env vars (whether set via Env or inherited from the
+// current process) anywhere this command logs them, including the command string, the verbose
+// env var output, and errors.
+func (c *Command) RedactEnv(keys ...string) *Command {
+	c.redactKeys = append(c.redactKeys, keys...)
+	return c
+}
+
+// String returns the command string, with the values of any env vars passed to RedactEnv hidden.
+func (c *Command) String() string {
+	return c.redact(c.raw)
+}
+
+// Context sets a context that kills the process if it is cancelled or reaches its deadline before
+// the process exits. When that happens, the error returned (or panicked) by the runner wraps
+// context.Canceled or context.DeadlineExceeded, which can be checked via errors.Is.
+func (c *Command) Context(ctx context.Context) *Command {
+	c.ctx = ctx
+	return c
+}
+
+// Timeout kills the process (and on Unix, any processes it started in its process group) if it
+// hasn't exited within d. When that happens, the error returned (or panicked) by the runner says
+// the timeout was exceeded, and wraps context.DeadlineExceeded. Can be combined with Context.
+// On Unix, the process is started in its own process group, unless its stdin is a terminal, in
+// which case it stays in the terminal's foreground group (so it still receives Ctrl-C, and can read
+// from the terminal), and only the process itself is killed when the timeout is exceeded.
+func (c *Command) Timeout(d time.Duration) *Command {
+	c.timeout = d
+	return c
+}
+
+// Dir sets the working directory of the process, without changing the working directory of the
+// current process (unlike InDir, this is safe to use from multiple goroutines). The folder isn't
+// checked until the command runs, so if it doesn't exist, the runner's usual error handling applies.
 func (c *Command) Dir(dir string) *Command {
 	c.dir = dir
 	return c
@@ -108,8 +296,8 @@ func (c *Command) Dir(dir string) *Command {
 
 func (c *Command) Run() {
 	if err := c.run(); err != nil {
-		c.b.Warnf("unexpected error in %s", c.raw)
-		c.b.Panic(err)
+		c.b.Warnf("unexpected error in %s", c.String())
+		c.b.Panic(c.withTail(err))
 	}
 }
 
@@ -118,29 +306,245 @@ func (c *Command) RunStr() string {
 	c.out = &b
 	c.err = &b
 	if err := c.run(); err != nil {
-		c.b.Warnf("unexpected error in %s", c.raw)
-		c.b.Panic(err)
+		c.b.Warnf("unexpected error in %s", c.String())
+		c.b.Panic(c.withTail(err))
 	}
 	return b.String()
 }
 
+// RunStrErr is RunStr, but instead of panicking on error, it returns the error along with
+// whatever output was captured.
+func (c *Command) RunStrErr() (string, error) {
+	var b strings.Builder
+	c.out = &b
+	c.err = &b
+	err := c.run()
+	return b.String(), err
+}
+
+// RunBytes is RunStrErr, but returns the captured output as a byte slice, which is useful when
+// the output may be binary.
+func (c *Command) RunBytes() ([]byte, error) {
+	var buf bytes.Buffer
+	c.out = &buf
+	c.err = &buf
+	err := c.run()
+	return buf.Bytes(), err
+}
+
+// RunOutErr captures stdout and stderr separately, and returns both, along with any error from
+// running the command. If a writer was explicitly set via Out, Err, or OutErr, that writer still
+// receives its output, in addition to it being captured.
+func (c *Command) RunOutErr() (stdout string, stderr string, err error) {
+	var outb, errb strings.Builder
+	c.captureOutErr(&outb, &errb)
+	err = c.run()
+	return outb.String(), errb.String(), err
+}
+
+// RunStrNonEmpty captures stdout, and returns it with leading and trailing whitespace trimmed.
+// If the trimmed output is empty, that is treated as an error (even if the exit status was 0),
+// and is handled by this instance of Bsh.
+func (c *Command) RunStrNonEmpty() string {
+	var sb strings.Builder
+	c.out = &sb
+	if err := c.run(); err != nil {
+		c.b.Warnf("unexpected error in %s", c.String())
+		c.b.Panic(c.withTail(err))
+	}
+	str := strings.TrimSpace(sb.String())
+	if len(str) == 0 {
+		c.b.Panic(fmt.Errorf("%s produced no output", c.String()))
+	}
+	return str
+}
+
+// RunStrLimit is like RunStr, but only keeps the first maxBytes of output. Any output beyond
+// that is discarded (allowing the process to run to completion), and true is returned to
+// indicate the output was truncated.
+func (c *Command) RunStrLimit(maxBytes int) (string, bool) {
+	lb := &limitedBuffer{max: maxBytes}
+	c.out = lb
+	c.err = lb
+	if err := c.run(); err != nil {
+		c.b.Warnf("unexpected error in %s", c.String())
+		c.b.Panic(c.withTail(err))
+	}
+	return lb.buf.String(), lb.truncated
+}
+
+// RunProduces is Run, but it also verifies that the command created a regular file at
+// expectedPath. If not, the error is handled by this instance of Bsh.
+func (c *Command) RunProduces(expectedPath string) {
+	c.Run()
+	fi, err := os.Stat(expectedPath)
+	if err != nil || !fi.Mode().IsRegular() {
+		c.b.Panic(fmt.Errorf("%s did not produce a file at %s", c.String(), expectedPath))
+	}
+}
+
+// RunToFileWithHash runs the command with its stdout written to a newly created file at path,
+// and returns the hex-encoded SHA256 digest of that output, computed as it is written.
+func (c *Command) RunToFileWithHash(path string) (string, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	c.out = io.MultiWriter(f, h)
+	if err := c.run(); err != nil {
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 func (c *Command) RunErr() error {
 	return c.run()
 }
 
+// RunCtxErr runs the command with the given context (see Context), and returns any error instead
+// of panicking, which makes it easy to tell a cancellation apart from any other failure.
+func (c *Command) RunCtxErr(ctx context.Context) error {
+	return c.Context(ctx).run()
+}
+
 func (c *Command) RunExitStatus() int {
 	n, err := extractExitStatus(c.run())
 	if err != nil {
-		c.b.Warnf("unexpected error in %s", c.raw)
-		c.b.Panic(err)
+		c.b.Warnf("unexpected error in %s", c.String())
+		c.b.Panic(c.withTail(err))
 	}
 	return n
 }
 
+// RunStatus runs the command and reports how it exited. If the process was terminated by a
+// signal (eg SIGKILL from the OOM killer), signaled is true and signal names the signal, and code
+// is -1. Otherwise code is the process's exit status. Errors that prevent the command from
+// running at all are returned as err. Signals are never reported on Windows.
+func (c *Command) RunStatus() (code int, signaled bool, signal string, err error) {
+	err = c.run()
+	if err == nil {
+		return 0, false, "", nil
+	}
+	var ee *exec.ExitError
+	if !errors.As(err, &ee) {
+		return -1, false, "", err
+	}
+	signaled, signal = exitSignal(ee.ProcessState)
+	return ee.ExitCode(), signaled, signal, nil
+}
+
+// ExpectExit runs the command and returns an error if its exit status is anything other than
+// code. Errors that prevent the command from running at all are also returned.
+func (c *Command) ExpectExit(code int) error {
+	n, err := extractExitStatus(c.run())
+	if err != nil {
+		return err
+	}
+	if n != code {
+		return fmt.Errorf("expected exit status %d from %s, but got %d", code, c.String(), n)
+	}
+	return nil
+}
+
+// RunJSONLines runs the command and calls fn with each line of stdout, as each line arrives.
+// Each non-empty line must be a complete JSON value (ie newline-delimited JSON).
+// After fn returns an error (or an invalid line is encountered), further lines are discarded.
+// The first such error is returned once the process exits, otherwise any run error is returned.
+func (c *Command) RunJSONLines(fn func(raw json.RawMessage) error) error {
+	var fnErr error
+	lw := newLineWriter(io.Discard, func(line string) string {
+		line = strings.TrimSpace(line)
+		if fnErr != nil || len(line) == 0 {
+			return ""
+		}
+		if !json.Valid([]byte(line)) {
+			fnErr = fmt.Errorf("invalid JSON line from %s: %q", c.String(), line)
+			return ""
+		}
+		fnErr = fn(json.RawMessage(line))
+		return ""
+	})
+	c.lineWriters = append(c.lineWriters, lw)
+	c.out = lw
+	err := c.run()
+	if fnErr != nil {
+		return fnErr
+	}
+	return err
+}
+
+// RunTable runs the command, then splits its stdout into lines, and each non-empty line into
+// whitespace-separated fields. This is useful for parsing the column-aligned output of tools
+// like ps or df.
+func (c *Command) RunTable() ([][]string, error) {
+	var sb strings.Builder
+	c.out = &sb
+	err := c.run()
+	return parseTable(sb.String()), err
+}
+
+// RunTableNoHeader is RunTable, but with the first row (ie the header) removed.
+func (c *Command) RunTableNoHeader() ([][]string, error) {
+	rows, err := c.RunTable()
+	if len(rows) > 0 {
+		rows = rows[1:]
+	}
+	return rows, err
+}
+
+// RunLines calls fn with each line the process writes to stdout (without its line ending), as
+// soon as the line is written. Any trailing partial line is passed to fn when the process exits.
+// Stderr is written to its usual destination. Returns any error from running the process.
+func (c *Command) RunLines(fn func(line string)) error {
+	c.out = c.newLineCallback(fn)
+	return c.run()
+}
+
+// RunCombinedLines is RunLines, but fn is called with each line from both stdout and stderr.
+func (c *Command) RunCombinedLines(fn func(line string)) error {
+	lw := c.newLineCallback(fn)
+	c.out = lw
+	c.err = lw
+	return c.run()
+}
+
+// newLineCallback returns a writer that calls fn with each line written to it, and is flushed
+// when the process exits
+func (c *Command) newLineCallback(fn func(line string)) io.Writer {
+	lw := newLineWriter(io.Discard, func(line string) string {
+		fn(strings.TrimSuffix(line, "\r"))
+		return ""
+	})
+	c.lineWriters = append(c.lineWriters, lw)
+	return lw
+}
+
+// RunLastFields captures stdout, and returns the last non-empty line split into whitespace
+// separated fields. This is useful for tools that print a summary on their final line.
+func (c *Command) RunLastFields() []string {
+	var sb strings.Builder
+	c.out = &sb
+	if err := c.run(); err != nil {
+		c.b.Warnf("unexpected error in %s", c.String())
+		c.b.Panic(c.withTail(err))
+	}
+	lines := splitLines(sb.String())
+	if len(lines) == 0 {
+		return nil
+	}
+	return strings.Fields(lines[len(lines)-1])
+}
+
 func (c *Command) Bash() {
 	if err := c.bash(); err != nil {
-		c.b.Warnf("unexpected error in bash -c %s", c.raw)
-		c.b.Panic(err)
+		c.b.Warnf("unexpected error in bash -c %s", c.String())
+		c.b.Panic(c.withTail(err))
 	}
 }
 
@@ -149,73 +553,330 @@ func (c *Command) BashStr() string {
 	c.out = &b
 	c.err = &b
 	if err := c.bash(); err != nil {
-		c.b.Warnf("unexpected error in bash -c %s", c.raw)
-		c.b.Panic(err)
+		c.b.Warnf("unexpected error in bash -c %s", c.String())
+		c.b.Panic(c.withTail(err))
 	}
 	return b.String()
 }
 
+// BashStrErr is BashStr, but instead of panicking on error, it returns the error along with
+// whatever output was captured.
+func (c *Command) BashStrErr() (string, error) {
+	var b strings.Builder
+	c.out = &b
+	c.err = &b
+	err := c.bash()
+	return b.String(), err
+}
+
+// BashBytes is RunBytes, but runs the command via "bash -c".
+func (c *Command) BashBytes() ([]byte, error) {
+	var buf bytes.Buffer
+	c.out = &buf
+	c.err = &buf
+	err := c.bash()
+	return buf.Bytes(), err
+}
+
 func (c *Command) BashErr() error {
 	return c.bash()
 }
 
+// BashOutErr is RunOutErr, but runs the command via "bash -c".
+func (c *Command) BashOutErr() (stdout string, stderr string, err error) {
+	var outb, errb strings.Builder
+	c.captureOutErr(&outb, &errb)
+	err = c.bash()
+	return outb.String(), errb.String(), err
+}
+
 func (c *Command) BashExitStatus() int {
 	n, err := extractExitStatus(c.bash())
 	if err != nil {
-		c.b.Warnf("unexpected error in bash -c %s", c.raw)
-		c.b.Panic(err)
+		c.b.Warnf("unexpected error in bash -c %s", c.String())
+		c.b.Panic(c.withTail(err))
 	}
 	return n
 }
 
+// RunNativeShell runs the command string via the native shell of the current OS, which is
+// "sh -c" everywhere except Windows, where it is "cmd /C". Shell syntax is not portable between
+// these shells, so this is only useful for simple commands (including simple pipes and
+// redirects) that are valid in both.
+func (c *Command) RunNativeShell() {
+	if err := c.nativeShell(); err != nil {
+		c.b.Warnf("unexpected error in native shell: %s", c.String())
+		c.b.Panic(c.withTail(err))
+	}
+}
+
 // helpers
 
 func (c *Command) run() error {
-	args, err := commandline.Parse(c.raw)
+	name, args, err := c.parse()
 	if err != nil {
 		return err
 	}
-	c.b.Verbosef("Exec: %s", c.raw)
-	cmd := exec.Command(args[0], args[1:]...)
-	if len(c.env) > 0 {
-		c.b.Verbosef("+Env: %v", c.env)
-		cmd.Env = append(os.Environ(), c.env...)
+	c.b.Verbosef("Exec: %s", c.String())
+	return c.execute(name, args...)
+}
+
+func (c *Command) bash() error {
+	c.b.Verbosef("Bash: %s", c.String())
+	return c.execute("bash", "-c", c.raw)
+}
+
+func (c *Command) nativeShell() error {
+	c.b.Verbosef("Shell: %s", c.String())
+	if runtime.GOOS == "windows" {
+		return c.execute("cmd", "/C", c.raw)
 	}
-	cmd.Dir = c.dir
-	cmd.Stdin = c.in
-	cmd.Stdout = c.out
-	cmd.Stderr = c.err
-	err = cmd.Run()
-	if c.exitStatus != nil {
-		n, e := extractExitStatus(err)
-		if e == nil {
-			*c.exitStatus = n
+	return c.execute("sh", "-c", c.raw)
+}
+
+// captureOutErr sends stdout to outw and stderr to errw, or if either was set explicitly (via
+// Out, Err, or OutErr), to both it and the explicitly set writer.
+func (c *Command) captureOutErr(outw, errw io.Writer) {
+	if c.outSet && c.errSet && sameWriter(c.out, c.err) {
+		// stdout and stderr are copied from separate goroutines, so the shared writer needs a lock
+		shared := &syncWriter{w: c.out}
+		c.out = shared
+		c.err = shared
+	}
+	if c.outSet {
+		outw = teeWriter(c.out, outw)
+	}
+	if c.errSet {
+		errw = teeWriter(c.err, errw)
+	}
+	c.out = outw
+	c.err = errw
+}
+
+// parse splits the raw command string into the name of the executable and its args
+func (c *Command) parse() (string, []string, error) {
+	args := c.args
+	if args == nil {
+		var err error
+		if args, err = commandline.Parse(c.raw); err != nil {
+			return "", nil, err
 		}
 	}
-	return err
+	if len(args) == 0 {
+		return "", nil, fmt.Errorf("empty command")
+	}
+	name := args[0]
+	if path, ok := c.envValue("PATH"); ok {
+		name = lookPathIn(name, path)
+	}
+	return name, args[1:], nil
 }
 
-func (c *Command) bash() error {
-	c.b.Verbosef("Bash: %s", c.raw)
-	cmd := exec.Command("bash", "-c", c.raw)
-	if len(c.env) > 0 {
-		c.b.Verbosef("+Env: %v", c.env)
+// execute builds and runs an exec.Cmd with all of this Command's modifiers applied
+func (c *Command) execute(name string, args ...string) error {
+	cmd := c.newCmd(name, args...)
+	if err := cmd.Start(); err != nil {
+		return c.finish(err)
+	}
+	c.watchTimeout(cmd)
+	return c.finish(cmd.Wait())
+}
+
+// newCmd builds an exec.Cmd with all of this Command's modifiers applied
+func (c *Command) newCmd(name string, args ...string) *exec.Cmd {
+	name, args = c.withRLimits(name, args)
+	ctx := c.ctx
+	if c.timeout > 0 {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		ctx, c.cancelTimeout = context.WithTimeout(ctx, c.timeout)
+		c.timeoutCtx = ctx
+	}
+	var cmd *exec.Cmd
+	if ctx != nil {
+		cmd = exec.CommandContext(ctx, name, args...)
+	} else {
+		cmd = exec.Command(name, args...)
+	}
+	// a new process group isn't the terminal's foreground group, so a process in one wouldn't see
+	// Ctrl-C, and would be stopped if it read from the terminal
+	if c.timeout > 0 && !isTerminalReader(c.in) {
+		setProcessGroup(cmd)
+	}
+	if c.cleanEnv {
+		c.b.Verbosef("+Env (clean): %v", c.redact(fmt.Sprint(c.env)))
+		cmd.Env = append([]string{}, c.env...)
+	} else if len(c.env) > 0 {
+		c.b.Verbosef("+Env: %v", c.redact(fmt.Sprint(c.env)))
 		cmd.Env = append(os.Environ(), c.env...)
 	}
+	if len(c.dir) > 0 {
+		c.b.Verbosef("+Dir: %s", c.dir)
+	}
 	cmd.Dir = c.dir
 	cmd.Stdin = c.in
 	cmd.Stdout = c.out
 	cmd.Stderr = c.err
-	err := cmd.Run()
+	if c.tail != nil {
+		c.tail.Reset()
+		cmd.Stdout = teeWriter(c.out, c.tail)
+		cmd.Stderr = teeWriter(c.err, c.tail)
+		if sameWriter(c.out, c.err) {
+			// a single writer lets os/exec copy both streams from one goroutine
+			cmd.Stderr = cmd.Stdout
+		}
+	}
+	return cmd
+}
+
+// watchTimeout kills the process group of the started cmd if Timeout's deadline passes before
+// finish is called. exec.CommandContext only kills the process itself.
+func (c *Command) watchTimeout(cmd *exec.Cmd) {
+	if c.timeoutCtx == nil {
+		return
+	}
+	ctx, pid := c.timeoutCtx, cmd.Process.Pid
+	go func() {
+		<-ctx.Done()
+		if ctx.Err() == context.DeadlineExceeded {
+			killProcessGroup(pid)
+		}
+	}()
+}
+
+// finish does any cleanup needed after the process exits, and records its exit status
+func (c *Command) finish(err error) error {
+	c.flushLineWriters()
+	if c.cancelTimeout != nil {
+		timedOut := c.timeoutCtx.Err() == context.DeadlineExceeded && (c.ctx == nil || c.ctx.Err() == nil)
+		c.cancelTimeout()
+		c.cancelTimeout = nil
+		c.timeoutCtx = nil
+		if err != nil && timedOut {
+			err = fmt.Errorf("timeout of %v exceeded running %s: %w", c.timeout, c.String(), context.DeadlineExceeded)
+		}
+	}
 	if c.exitStatus != nil {
 		n, e := extractExitStatus(err)
 		if e == nil {
 			*c.exitStatus = n
 		}
 	}
+	if err != nil && c.ctx != nil && c.ctx.Err() != nil && !errors.Is(err, c.ctx.Err()) {
+		err = fmt.Errorf("%w (%v)", c.ctx.Err(), err)
+	}
 	return err
 }
 
+func (c *Command) flushLineWriters() {
+	for _, lw := range c.lineWriters {
+		lw.Flush()
+	}
+}
+
+// withTail adds any output retained by TailBuffer to the given error
+func (c *Command) withTail(err error) error {
+	if c.tail == nil || c.tail.Len() == 0 {
+		return err
+	}
+	return fmt.Errorf("%w\nlast output:\n%s", err, c.redact(c.tail.String()))
+}
+
+// redact replaces the values of any env vars passed to RedactEnv in str with asterisks
+func (c *Command) redact(str string) string {
+	for _, key := range c.redactKeys {
+		value, ok := c.envValue(key)
+		if !ok {
+			value = os.Getenv(key)
+		}
+		if len(value) > 0 {
+			str = strings.ReplaceAll(str, value, "******")
+		}
+	}
+	return str
+}
+
+// envValue returns the value of the last env var set on this command with the given key
+func (c *Command) envValue(key string) (string, bool) {
+	for i := len(c.env) - 1; i >= 0; i-- {
+		kv := c.env[i]
+		n := strings.Index(kv, "=")
+		if n < 0 {
+			continue
+		}
+		if kv[:n] == key || (runtime.GOOS == "windows" && strings.EqualFold(kv[:n], key)) {
+			return kv[n+1:], true
+		}
+	}
+	return "", false
+}
+
+// lookPathIn searches the folders in path for an executable with the given name. If name
+// already contains a path separator, or if no match is found, name is returned unchanged,
+// leaving it to exec.Command to search the PATH of the current process.
+func lookPathIn(name, path string) string {
+	if strings.ContainsAny(name, `/\`) {
+		return name
+	}
+	candidates := []string{name}
+	if runtime.GOOS == "windows" && len(filepath.Ext(name)) == 0 {
+		candidates = append(candidates, ExeName(name))
+	}
+	for _, dir := range filepath.SplitList(path) {
+		if len(dir) == 0 {
+			continue
+		}
+		for _, candidate := range candidates {
+			full := filepath.Join(dir, candidate)
+			fi, err := os.Stat(full)
+			if err != nil || !fi.Mode().IsRegular() {
+				continue
+			}
+			if runtime.GOOS != "windows" && fi.Mode().Perm()&0111 == 0 {
+				continue
+			}
+			return full
+		}
+	}
+	return name
+}
+
+// splitLines splits str into lines, handling both \n and \r\n line endings, and dropping any
+// lines that are empty or only whitespace.
+func splitLines(str string) []string {
+	lines := make([]string, 0, 16)
+	for _, line := range strings.Split(str, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if len(strings.TrimSpace(line)) > 0 {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// quoteArg wraps arg in single quotes if it contains anything other than characters that are safe
+// to use unquoted in a shell command, so that it reads unambiguously in logs
+func quoteArg(arg string) string {
+	if len(arg) > 0 && strings.IndexFunc(arg, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=:,+@%", r))
+	}) < 0 {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+func parseTable(str string) [][]string {
+	rows := make([][]string, 0, 16)
+	for _, line := range strings.Split(str, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			rows = append(rows, fields)
+		}
+	}
+	return rows
+}
+
 func extractExitStatus(err error) (int, error) {
 	if err == nil {
 		return 0, nil
@@ -0,0 +1,460 @@
+package bsh
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/creack/pty"
+)
+
+func TestDir(t *testing.T) {
+	ensureLocalFolder(t)
+	b := Bsh{}
+	b.MkdirAll("local/dir_test")
+	expected, err := filepath.Abs("local/dir_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ = filepath.EvalSymlinks(expected)
+	cwd := b.Getwd()
+
+	actual := strings.TrimSpace(b.Cmd(`bash -c "pwd -P"`).Dir("local/dir_test").RunStr())
+	if actual != expected {
+		t.Errorf(`expected Dir + RunStr to output "%s", but got "%s"`, expected, actual)
+	}
+
+	actual = b.RunInDir("local/dir_test", `bash -c "pwd -P"`)
+	if actual != expected {
+		t.Errorf(`expected RunInDir to output "%s", but got "%s"`, expected, actual)
+	}
+
+	if b.Getwd() != cwd {
+		t.Errorf(`expected cwd to be unchanged, but it is now "%s"`, b.Getwd())
+	}
+
+	if err := b.Cmd(`bash -c "pwd"`).Dir("local/does_not_exist").RunErr(); err == nil {
+		t.Errorf("expected an error when Dir does not exist")
+	}
+}
+
+func TestRedactEnv(t *testing.T) {
+	var out strings.Builder
+	b := Bsh{Stdout: &out, DisableColor: true}
+	b.SetVerbose(true)
+	actual := b.Cmd("echo hunter2").Env("TOKEN=hunter2").RedactEnv("TOKEN").RunStr()
+	b.SetVerbose(false)
+
+	if strings.TrimSpace(actual) != "hunter2" {
+		t.Errorf(`expected command output to be unchanged, but got "%s"`, actual)
+	}
+	if strings.Contains(out.String(), "hunter2") {
+		t.Errorf("expected secret to be redacted from logs, but got:\n%s", out.String())
+	}
+}
+
+func TestRunLastFields(t *testing.T) {
+	b := Bsh{}
+	actual := b.Cmd(`bash -c "echo first line; echo ok 12 passed; echo"`).RunLastFields()
+	expected := []string{"ok", "12", "passed"}
+	if strings.Join(actual, "|") != strings.Join(expected, "|") {
+		t.Errorf(`expected %v, but got %v`, expected, actual)
+	}
+}
+
+func TestRunStatus(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals are not reported on windows")
+	}
+	b := Bsh{}
+	code, signaled, signal, err := b.Cmd(`bash -c "exit 3"`).RunStatus()
+	if err != nil || code != 3 || signaled {
+		t.Errorf(`expected exit 3 without signal, but got %d, %v, "%s", %v`, code, signaled, signal, err)
+	}
+
+	code, signaled, signal, err = b.Cmd(`bash -c "kill -KILL $$"`).RunStatus()
+	if err != nil || !signaled || signal != "killed" {
+		t.Errorf(`expected to be killed by signal, but got %d, %v, "%s", %v`, code, signaled, signal, err)
+	}
+}
+
+func TestRunStreaming(t *testing.T) {
+	b := Bsh{}
+	s := b.Cmd(`bash -c "echo one; echo two >&2; printf three"`).RunStreaming()
+
+	<-s.Done()
+	if s.Err() != nil {
+		t.Fatalf("unexpected error: %v", s.Err())
+	}
+
+	// only the order of lines within each stream is checked, since whether stdout and stderr are
+	// interleaved in the order they were written depends on whether they share a pipe
+	var stdout []string
+	var stderrCount int
+	for _, line := range s.Lines() {
+		if line == "two" {
+			stderrCount++
+		} else {
+			stdout = append(stdout, line)
+		}
+	}
+	if actual := strings.Join(stdout, ","); actual != "one,three" {
+		t.Errorf(`expected stdout lines "one,three", but got "%s"`, actual)
+	}
+	if stderrCount != 1 {
+		t.Errorf(`expected stderr line "two" once, but got it %d time(s)`, stderrCount)
+	}
+}
+
+func TestRunCtxErr(t *testing.T) {
+	b := Bsh{}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := b.Cmd("sleep 5").RunCtxErr(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error to wrap context.DeadlineExceeded, but got %v", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("expected process to be killed when the deadline passed")
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	b := Bsh{}
+	var out strings.Builder
+	start := time.Now()
+	// the sleep is a child of bash, and holds the output pipe open unless it is also killed
+	err := b.Cmd(`bash -c "sleep 5; echo done"`).OutErr(&out).Timeout(200 * time.Millisecond).RunErr()
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "timeout of 200ms exceeded") {
+		t.Errorf("expected a timeout error, but got %v", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("expected process group to be killed when the timeout passed")
+	}
+
+	if err := b.Cmd("true").Timeout(time.Second).RunErr(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRunOutErr(t *testing.T) {
+	b := Bsh{}
+	stdout, stderr, err := b.Cmd(`bash -c "echo out; echo err >&2"`).RunOutErr()
+	if err != nil || stdout != "out\n" || stderr != "err\n" {
+		t.Errorf(`expected "out\n", "err\n", nil, but got "%s", "%s", %v`, stdout, stderr, err)
+	}
+
+	var explicit strings.Builder
+	stdout, stderr, err = b.Cmd(`echo out; echo err >&2; exit 2`).Out(&explicit).BashOutErr()
+	if err == nil || stdout != "out\n" || stderr != "err\n" {
+		t.Errorf(`expected "out\n", "err\n", and an error, but got "%s", "%s", %v`, stdout, stderr, err)
+	}
+	if explicit.String() != "out\n" {
+		t.Errorf(`expected explicit Out to also receive "out\n", but got "%s"`, explicit.String())
+	}
+}
+
+func TestRunAllResults(t *testing.T) {
+	b := Bsh{}
+	commands := func() []*Command {
+		return []*Command{
+			b.Cmd(`bash -c "echo first"`),
+			b.Cmd(`bash -c "echo second >&2; exit 3"`),
+			b.Cmd(`bash -c "echo third"`),
+		}
+	}
+
+	for _, results := range [][]CommandResult{
+		b.RunAllResults(commands()...),
+		b.RunAllResultsParallel(2, commands()...),
+	} {
+		if len(results) != 3 {
+			t.Fatalf("expected 3 results, but got %d", len(results))
+		}
+		if results[0].Stdout != "first\n" || results[0].Err != nil {
+			t.Errorf("unexpected first result: %+v", results[0])
+		}
+		if results[1].Stderr != "second\n" || results[1].ExitStatus != 3 || results[1].Err == nil {
+			t.Errorf("unexpected second result: %+v", results[1])
+		}
+		if results[2].Stdout != "third\n" || results[2].Err != nil {
+			t.Errorf("unexpected third result: %+v", results[2])
+		}
+	}
+}
+
+func TestStdinStr(t *testing.T) {
+	b := Bsh{}
+	if actual := b.Cmd("cat").StdinStr("hello\n").RunStr(); actual != "hello\n" {
+		t.Errorf(`expected "hello\n", but got "%s"`, actual)
+	}
+	if actual := b.Cmd("cat").StdinBytes([]byte("bytes\n")).RunStr(); actual != "bytes\n" {
+		t.Errorf(`expected "bytes\n", but got "%s"`, actual)
+	}
+}
+
+func TestRunStrErrKeepsOutputOnFailure(t *testing.T) {
+	b := Bsh{}
+	out, err := b.Cmd(`bash -c "echo partial; echo reason >&2; exit 1"`).RunStrErr()
+	if err == nil {
+		t.Errorf("expected an error")
+	}
+	if out != "partial\nreason\n" {
+		t.Errorf(`expected output captured before the failure, but got "%s"`, out)
+	}
+}
+
+func TestRunLines(t *testing.T) {
+	b := Bsh{}
+	long := strings.Repeat("x", 200*1024)
+	var lines []string
+	script := `printf 'one\r\n'; head -c 204800 /dev/zero | tr '\0' x; printf '\ntrailing'`
+	err := b.Cmd(`bash -c "` + script + `"`).RunLines(func(line string) {
+		lines = append(lines, line)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lines) != 3 || lines[0] != "one" || lines[1] != long || lines[2] != "trailing" {
+		t.Errorf("unexpected lines (count %d): %.40q", len(lines), lines)
+	}
+
+	lines = nil
+	err = b.Cmd(`bash -c "echo out; sleep 0.1; echo err >&2"`).RunCombinedLines(func(line string) {
+		lines = append(lines, line)
+	})
+	if err != nil || strings.Join(lines, ",") != "out,err" {
+		t.Errorf(`expected "out,err", but got "%s" (%v)`, strings.Join(lines, ","), err)
+	}
+}
+
+func TestRunBytes(t *testing.T) {
+	b := Bsh{}
+	out, err := b.Cmd(`printf 'a\000b'`).BashBytes()
+	if err != nil || !bytes.Equal(out, []byte{'a', 0, 'b'}) {
+		t.Errorf("expected bytes a, 0, b, but got %v (%v)", out, err)
+	}
+}
+
+func TestCmdArgs(t *testing.T) {
+	b := Bsh{}
+	c := b.CmdArgs("printf", `%s|`, "has space", `it's "quoted"`)
+	if c.String() != `printf '%s|' 'has space' 'it'\''s "quoted"'` {
+		t.Errorf("unexpected command string: %s", c.String())
+	}
+	if actual := c.RunStr(); actual != `has space|it's "quoted"|` {
+		t.Errorf(`expected args to be passed verbatim, but got "%s"`, actual)
+	}
+}
+
+func TestCmdArgsExpandEnv(t *testing.T) {
+	os.Setenv("BSH_TEST_EXPAND", "has space")
+	defer os.Unsetenv("BSH_TEST_EXPAND")
+	b := Bsh{}
+
+	actual := b.CmdArgs("printf", `%s|`, "$BSH_TEST_EXPAND", "x${BSH_TEST_EXPAND}").ExpandEnv().RunStr()
+	if actual != "has space|xhas space|" {
+		t.Errorf(`expected each arg to be expanded, but got "%s"`, actual)
+	}
+}
+
+func TestCleanEnv(t *testing.T) {
+	os.Setenv("BSH_TEST_INHERITED", "yes")
+	defer os.Unsetenv("BSH_TEST_INHERITED")
+	b := Bsh{}
+
+	actual := b.Cmd(`/usr/bin/env`).CleanEnv("ONLY=this").RunStr()
+	if actual != "ONLY=this\n" {
+		t.Errorf(`expected only "ONLY=this", but got "%s"`, actual)
+	}
+
+	actual = b.Cmd(`/usr/bin/env`).Env("ADDED=too").RunStr()
+	if !strings.Contains(actual, "BSH_TEST_INHERITED=yes\n") || !strings.Contains(actual, "ADDED=too\n") {
+		t.Errorf("expected Env to add to the inherited environment, but got:\n%s", actual)
+	}
+}
+
+func TestTailBufferOutErr(t *testing.T) {
+	b := Bsh{}
+	var sb strings.Builder
+	c := b.Cmd(`for i in $(seq 100); do echo out; echo err >&2; done`).OutErr(&sb).TailBuffer(8)
+	c.Bash()
+
+	if n := strings.Count(sb.String(), "\n"); n != 200 {
+		t.Errorf("expected 200 lines of output, but got %d", n)
+	}
+	if actual := c.LastOutput(); actual != "out\nerr\n" {
+		t.Errorf(`expected last output "out\nerr\n", but got "%s"`, actual)
+	}
+}
+
+func TestStartOutErr(t *testing.T) {
+	b := Bsh{}
+	var sb strings.Builder
+	rc := b.Cmd(`bash -c "seq 100 | while read i; do echo out; echo err >&2; done"`).OutErr(&sb).Start()
+	if err := rc.Wait(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n := strings.Count(sb.String(), "\n"); n != 200 {
+		t.Errorf("expected 200 lines of output, but got %d", n)
+	}
+	if !rc.WaitForOutput(regexp.MustCompile(`^err$`), time.Second) {
+		t.Errorf("expected stderr lines to be retained")
+	}
+}
+
+func TestRunOutErrShared(t *testing.T) {
+	b := Bsh{}
+	var sb strings.Builder
+	stdout, stderr, err := b.Cmd(`bash -c "seq 100 | while read i; do echo out; echo err >&2; done"`).OutErr(&sb).RunOutErr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if stdout != strings.Repeat("out\n", 100) || stderr != strings.Repeat("err\n", 100) {
+		t.Errorf("expected stdout and stderr to be captured separately, but got:\n%s\n%s", stdout, stderr)
+	}
+	if n := strings.Count(sb.String(), "\n"); n != 200 {
+		t.Errorf("expected 200 lines written to the shared writer, but got %d", n)
+	}
+}
+
+func TestColorStderr(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("pseudo-terminals are not supported on windows")
+	}
+	// color is only used when Stdout is a terminal
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		t.Skipf("unable to open a pseudo-terminal: %v", err)
+	}
+	defer ptmx.Close()
+	defer tty.Close()
+	restoreEnv := func(key string) func() {
+		old, exists := os.LookupEnv(key)
+		return func() {
+			if exists {
+				os.Setenv(key, old)
+			} else {
+				os.Unsetenv(key)
+			}
+		}
+	}
+	defer restoreEnv("TERM")()
+	defer restoreEnv("NO_COLOR")()
+	os.Setenv("TERM", "xterm")
+	os.Unsetenv("NO_COLOR")
+
+	var errb bytes.Buffer
+	b := Bsh{Stdout: tty, Stderr: &errb}
+	b.Cmd(`bash -c "echo one >&2; printf two >&2"`).ColorStderr().Run()
+	expected := ansiRed + "one" + ansiReset + "\n" + ansiRed + "two" + ansiReset
+	if actual := errb.String(); actual != expected {
+		t.Errorf("expected %q, but got %q", expected, actual)
+	}
+
+	errb.Reset()
+	b.DisableColor = true
+	b.Cmd(`bash -c "echo one >&2"`).ColorStderr().Run()
+	if actual := errb.String(); actual != "one\n" {
+		t.Errorf(`expected "one\n" without color, but got %q`, actual)
+	}
+}
+
+func TestPrependPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the command")
+	}
+	ensureLocalFolder(t)
+	b := Bsh{}
+	dir, err := filepath.Abs("local/prepend_path_bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.RemoveAll(dir)
+	b.MkdirAll(dir)
+	b.Write(filepath.Join(dir, "bsh-prepend-test"), "#!/bin/sh\necho \"found ${PATH%%:*}\"\n")
+	b.Chmod(filepath.Join(dir, "bsh-prepend-test"), 0755)
+	before := os.Getenv("PATH")
+
+	actual := b.Cmd("bsh-prepend-test").PrependPath(dir).RunStr()
+	if actual != "found "+dir+"\n" {
+		t.Errorf(`expected the command to be found in dir, with dir first in PATH, but got "%s"`, actual)
+	}
+	if os.Getenv("PATH") != before {
+		t.Error("expected the PATH of the current process to be unchanged")
+	}
+}
+
+func TestRunJSONLines(t *testing.T) {
+	b := Bsh{}
+	var ids []int
+	collect := func(raw json.RawMessage) error {
+		var v struct{ ID int }
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		ids = append(ids, v.ID)
+		return nil
+	}
+
+	err := b.CmdArgs("printf", `{"id":1}\n\n{"id":2}\n{"id":3}`).RunJSONLines(collect)
+	if err != nil || fmt.Sprint(ids) != "[1 2 3]" {
+		t.Errorf("expected ids [1 2 3], but got %v (%v)", ids, err)
+	}
+
+	ids = nil
+	err = b.CmdArgs("printf", `{"id":1}\nnot json\n{"id":2}\n`).RunJSONLines(collect)
+	if err == nil || !strings.Contains(err.Error(), "invalid JSON line") {
+		t.Errorf("expected an invalid JSON line error, but got %v", err)
+	}
+	if fmt.Sprint(ids) != "[1]" {
+		t.Errorf("expected lines after the invalid one to be discarded, but got %v", ids)
+	}
+}
+
+func TestRunStrLimit(t *testing.T) {
+	b := Bsh{}
+	actual, truncated := b.Cmd(`bash -c "printf 0123456789; printf abc >&2"`).RunStrLimit(4)
+	if actual != "0123" || !truncated {
+		t.Errorf(`expected "0123" (truncated), but got "%s" (truncated: %v)`, actual, truncated)
+	}
+
+	actual, truncated = b.Cmd("printf 0123456789").RunStrLimit(10)
+	if actual != "0123456789" || truncated {
+		t.Errorf(`expected "0123456789" (not truncated), but got "%s" (truncated: %v)`, actual, truncated)
+	}
+}
+
+func TestRunToFileWithHash(t *testing.T) {
+	ensureLocalFolder(t)
+	b := Bsh{}
+	b.InDir("local", func() {
+		hash, err := b.Cmd(`bash -c "printf hello; printf ignored >&2"`).Err(io.Discard).RunToFileWithHash("hash_output.txt")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		contents := b.Read("hash_output.txt")
+		if contents != "hello" {
+			t.Errorf(`expected the file to hold stdout "hello", but it holds "%s"`, contents)
+		}
+		sum := sha256.Sum256([]byte(contents))
+		if expected := hex.EncodeToString(sum[:]); hash != expected {
+			t.Errorf("expected hash %s to match the file contents, but got %s", expected, hash)
+		}
+	})
+}
@@ -0,0 +1,25 @@
+package bsh
+
+import (
+	"fmt"
+)
+
+// DiskFree returns the number of bytes available to the current user on the filesystem that
+// contains path.
+func (b *Bsh) DiskFree(path string) uint64 {
+	free, err := diskFree(path)
+	if err != nil {
+		b.Panic(err)
+	}
+	b.Verbosef("DiskFree: %s has %d byte(s) available", path, free)
+	return free
+}
+
+// RequireDiskFree panics (via this instance of Bsh) if the filesystem that contains path has
+// fewer than the given number of bytes available.
+func (b *Bsh) RequireDiskFree(path string, bytes uint64) {
+	free := b.DiskFree(path)
+	if free < bytes {
+		b.Panic(fmt.Errorf("%s has %d byte(s) available, but at least %d byte(s) are required", path, free, bytes))
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !freebsd && !windows
+// +build !linux,!darwin,!freebsd,!windows
+
+package bsh
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func diskFree(path string) (uint64, error) {
+	return 0, fmt.Errorf("DiskFree is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,110 @@
+package bsh
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// when a dry run write would change a file with at most this many lines, a diff is shown
+const dryRunMaxDiffLines = 200
+
+// dryRunWrite echoes what writing (or appending) data to path would do, without doing it
+func (b *Bsh) dryRunWrite(path string, data []byte, isAppend bool) error {
+	old, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	exists := err == nil
+
+	switch {
+	case isAppend && exists:
+		b.dryRunf("would append %d byte(s) to %s (currently %d byte(s))", len(data), path, len(old))
+		return nil
+	case !exists:
+		b.dryRunf("would create %s with %d byte(s)", path, len(data))
+		return nil
+	}
+
+	if bytes.Equal(old, data) {
+		b.dryRunf("would write %s (no change)", path)
+		return nil
+	}
+	b.dryRunf("would write %d byte(s) to %s (currently %d byte(s))", len(data), path, len(old))
+
+	if bytes.IndexByte(old, 0) >= 0 || bytes.IndexByte(data, 0) >= 0 {
+		return nil
+	}
+	oldLines := strings.Split(string(old), "\n")
+	newLines := strings.Split(string(data), "\n")
+	if len(oldLines) > dryRunMaxDiffLines || len(newLines) > dryRunMaxDiffLines {
+		return nil
+	}
+	b.echo(strings.Join(lineDiff(oldLines, newLines), "\n"), ensureNewline, colorSkip)
+	return nil
+}
+
+// dryRunCopy echoes what copying src to dst would do, without doing it
+func (b *Bsh) dryRunCopy(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return err
+		}
+		return fmt.Errorf("error reading src %s: %w", src, err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", src)
+	}
+	b.dryRunf("would copy %s to %s (%d byte(s))", src, dst, info.Size())
+	return nil
+}
+
+func (b *Bsh) dryRunf(format string, args ...interface{}) {
+	b.echo("[dry run] "+fmt.Sprintf(format, args...), ensureNewline, colorWarn)
+}
+
+// lineDiff returns the lines that were removed from (prefixed by "-") or added to (prefixed by
+// "+") before to get after, based on the longest common subsequence of lines.
+func lineDiff(before, after []string) []string {
+	// lcs[i][j] is the length of the longest common subsequence of before[i:] and after[j:]
+	lcs := make([][]int, len(before)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	diff := make([]string, 0, 16)
+	i, j := 0, 0
+	for i < len(before) && j < len(after) {
+		switch {
+		case before[i] == after[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, "-"+before[i])
+			i++
+		default:
+			diff = append(diff, "+"+after[j])
+			j++
+		}
+	}
+	for ; i < len(before); i++ {
+		diff = append(diff, "-"+before[i])
+	}
+	for ; j < len(after); j++ {
+		diff = append(diff, "+"+after[j])
+	}
+	return diff
+}
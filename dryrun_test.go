@@ -0,0 +1,60 @@
+package bsh
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	ensureLocalFolder(t)
+	var out bytes.Buffer
+	b := Bsh{DisableColor: true, Stdout: &out}
+	b.InDir("local", func() {
+		b.Write("dryrun.txt", "one\ntwo\nthree\n")
+		b.RemoveAll("dryrun_src")
+		b.RemoveAll("dryrun_dst")
+		b.RemoveAll("dryrun_link.txt")
+		b.RemoveAll("dryrun_concat.txt")
+		b.MkdirAll("dryrun_src/sub")
+		b.MkdirAll("dryrun_dst")
+
+		b.DryRun = true
+		b.Write("dryrun.txt", "one\n2\nthree\n")
+		b.Copy("dryrun.txt", "dryrun_copy.txt")
+		rollback := b.WriteReversible("dryrun_new.txt", "new")
+		rollback()
+		b.CopyContents("dryrun_src", "dryrun_dst")
+		b.Link("dryrun.txt", "dryrun_link.txt")
+		b.Concat("dryrun_concat.txt", "dryrun.txt", "dryrun.txt")
+		b.DryRun = false
+
+		if actual := b.Read("dryrun.txt"); actual != "one\ntwo\nthree\n" {
+			t.Errorf(`expected dry run to leave file unchanged, but it contains "%s"`, actual)
+		}
+		if b.Exists("dryrun_copy.txt") {
+			t.Error("expected dry run to not create a copy")
+		}
+		if b.Exists("dryrun_dst/sub") {
+			t.Error("expected dry run to not create folders")
+		}
+		if b.Exists("dryrun_link.txt") || b.Exists("dryrun_concat.txt") {
+			t.Error("expected dry run to not link or concatenate files")
+		}
+	})
+
+	actual := out.String()
+	for _, expected := range []string{
+		"-two\n+2\n",
+		"would copy dryrun.txt to dryrun_copy.txt",
+		"would remove dryrun_new.txt",
+		"would create folder " + filepath.Join("dryrun_dst", "sub"),
+		"would link dryrun_link.txt to dryrun.txt",
+		"would concatenate 2 file(s) into dryrun_concat.txt",
+	} {
+		if !strings.Contains(actual, expected) {
+			t.Errorf(`expected dry run output to contain "%s", but got "%s"`, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package bsh
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EnvWithPrefix returns all environment variables whose name starts with prefix, as a map of
+// name to value. If stripPrefix is true, the prefix is removed from the names in the map.
+func (b *Bsh) EnvWithPrefix(prefix string, stripPrefix bool) map[string]string {
+	vars := make(map[string]string)
+	for _, kv := range os.Environ() {
+		i := strings.Index(kv, "=")
+		if i < 0 {
+			continue
+		}
+		key := kv[:i]
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if stripPrefix {
+			key = strings.TrimPrefix(key, prefix)
+		}
+		vars[key] = kv[i+1:]
+	}
+	return vars
+}
+
+// EnvFlag parses the named env var as a bool (see strconv.ParseBool for accepted values),
+// returning def if the env var is not set or can't be parsed.
+func (b *Bsh) EnvFlag(envVar string, def bool) bool {
+	v, err := strconv.ParseBool(os.Getenv(envVar))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// LoadDotEnv reads a .env file and sets each variable it defines in the environment of the
+// current process, overwriting any existing values. Each line is of the form "KEY=value", and may
+// start with "export ". Values may be wrapped in single quotes (taken literally) or double quotes
+// (where \n, \", and \\ escapes are expanded). Blank lines and lines starting with # are skipped.
+func (b *Bsh) LoadDotEnv(path string) {
+	b.Verbosef("LoadDotEnv: %s", path)
+	vars, err := parseDotEnv(b.Read(path))
+	if err != nil {
+		b.Panic(fmt.Errorf("%s: %w", path, err))
+		return
+	}
+	for _, kv := range vars {
+		if err := os.Setenv(kv[0], kv[1]); err != nil {
+			b.Panic(err)
+			return
+		}
+	}
+}
+
+// LoadDotEnvChain calls LoadDotEnv on each path in order, so that variables in later files
+// override those in earlier files. Paths that don't exist are skipped.
+func (b *Bsh) LoadDotEnvChain(paths ...string) {
+	for _, path := range paths {
+		if !b.Exists(path) {
+			b.Verbosef("LoadDotEnvChain: skipping missing %s", path)
+			continue
+		}
+		b.LoadDotEnv(path)
+	}
+}
+
+// parseDotEnv returns the key/value pairs from the contents of a .env file, in file order
+func parseDotEnv(contents string) ([][2]string, error) {
+	var vars [][2]string
+	for n, line := range strings.Split(contents, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		i := strings.Index(line, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("line %d: expected KEY=value", n+1)
+		}
+		key := strings.TrimSpace(line[:i])
+		value := strings.TrimSpace(line[i+1:])
+		if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = value[1 : len(value)-1]
+		} else if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = strings.NewReplacer(`\n`, "\n", `\"`, `"`, `\\`, `\`).Replace(value[1 : len(value)-1])
+		}
+		vars = append(vars, [2]string{key, value})
+	}
+	return vars, nil
+}
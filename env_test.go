@@ -0,0 +1,31 @@
+package bsh
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLoadDotEnvChain(t *testing.T) {
+	ensureLocalFolder(t)
+	b := Bsh{}
+	b.Write("local/root.env", "# root\nBSH_TEST_A=root\nexport BSH_TEST_B='single $quoted'\nBSH_TEST_C=\"line1\\nline2\"\n")
+	b.Write("local/service.env", "BSH_TEST_A = service\n")
+	defer func() {
+		os.Unsetenv("BSH_TEST_A")
+		os.Unsetenv("BSH_TEST_B")
+		os.Unsetenv("BSH_TEST_C")
+	}()
+
+	b.LoadDotEnvChain("local/root.env", "local/missing.env", "local/service.env")
+
+	expected := map[string]string{
+		"BSH_TEST_A": "service",
+		"BSH_TEST_B": "single $quoted",
+		"BSH_TEST_C": "line1\nline2",
+	}
+	for k, v := range expected {
+		if actual := os.Getenv(k); actual != v {
+			t.Errorf(`expected %s to be "%s", but got "%s"`, k, v, actual)
+		}
+	}
+}
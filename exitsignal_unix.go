@@ -0,0 +1,30 @@
+//go:build !windows
+// +build !windows
+
+package bsh
+
+import (
+	"os"
+	"syscall"
+)
+
+func exitSignal(ps *os.ProcessState) (bool, string) {
+	if ps == nil {
+		return false, ""
+	}
+	ws, ok := ps.Sys().(syscall.WaitStatus)
+	if !ok || !ws.Signaled() {
+		return false, ""
+	}
+	return true, ws.Signal().String()
+}
+
+// killedByBrokenPipe returns true if the process was terminated by SIGPIPE, which happens when it
+// writes to a pipe whose reader has exited
+func killedByBrokenPipe(ps *os.ProcessState) bool {
+	if ps == nil {
+		return false
+	}
+	ws, ok := ps.Sys().(syscall.WaitStatus)
+	return ok && ws.Signaled() && ws.Signal() == syscall.SIGPIPE
+}
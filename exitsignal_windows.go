@@ -0,0 +1,12 @@
+package bsh
+
+import "os"
+
+// Windows processes don't terminate via signals, so there is never any signal to report.
+func exitSignal(ps *os.ProcessState) (bool, string) {
+	return false, ""
+}
+
+func killedByBrokenPipe(ps *os.ProcessState) bool {
+	return false
+}
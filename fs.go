@@ -2,11 +2,15 @@ package bsh
 
 import (
 	"errors"
+	"fmt"
 	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"syscall"
+	"time"
 )
 
 // ExeName adds ".exe" to passed string if GOOS is windows
@@ -47,15 +51,29 @@ func (b *Bsh) MkdirAll(dir string) {
 	}
 }
 
+// EnsureParentDir creates the folder that contains path (and any intermediate folders), if it
+// doesn't already exist. Does nothing if path has no folder component.
+func (b *Bsh) EnsureParentDir(path string) {
+	b.Verbosef("EnsureParentDir: %s", path)
+	if err := ensureParentDir(path); err != nil {
+		b.Panic(err)
+	}
+}
+
+func ensureParentDir(path string) error {
+	dir := filepath.Dir(path)
+	if len(dir) == 0 || dir == "." || dir == "/" || dir == "\\" {
+		return nil
+	}
+	return os.MkdirAll(dir, os.ModePerm)
+}
+
 // Touch creates a file if it doesn't exist, and creates any intermediate folders needed.
 func (b *Bsh) Touch(path string) {
 	b.Verbosef("Touch: %s", path)
 
-	dir := filepath.Dir(path)
-	if len(dir) > 0 && dir != "." && dir != "/" && dir != "\\" {
-		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
-			b.Panic(err)
-		}
+	if err := ensureParentDir(path); err != nil {
+		b.Panic(err)
 	}
 
 	f, err := os.Create(path)
@@ -81,6 +99,19 @@ func (b *Bsh) RemoveAll(dir string) {
 	}
 }
 
+// RemoveAllMulti calls RemoveAll on each of the given paths. Paths that don't exist are ignored.
+func (b *Bsh) RemoveAllMulti(paths ...string) {
+	for _, path := range paths {
+		b.RemoveAll(path)
+	}
+}
+
+// RemoveGlob calls RemoveAll on each path that matches the given pattern (see filepath.Match for
+// the pattern syntax). If nothing matches, nothing is removed.
+func (b *Bsh) RemoveGlob(pattern string) {
+	b.ForEachGlob(pattern, b.RemoveAll)
+}
+
 // Exists checks if this path already exists on disc (as a file or folder or whatever)
 func (b *Bsh) Exists(path string) bool {
 	_, err := os.Stat(path)
@@ -117,6 +148,52 @@ func (b *Bsh) IsDir(path string) bool {
 	return fi.IsDir()
 }
 
+// IsWritable checks if this path can be written to. For an existing file, it attempts to open it
+// for writing. For an existing folder, it attempts to create (and then remove) a temporary file
+// inside it. For a path that doesn't exist, it checks the nearest parent folder that does exist.
+// Permission errors return false; other errors are handled by this instance of Bsh.
+func (b *Bsh) IsWritable(path string) bool {
+	fi, err := os.Stat(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			b.Panic(err)
+			return false
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return false
+		}
+		return b.IsWritable(parent)
+	}
+
+	var f *os.File
+	if fi.IsDir() {
+		f, err = os.CreateTemp(path, ".bsh_probe_*")
+	} else {
+		f, err = os.OpenFile(path, os.O_WRONLY, 0)
+	}
+	if err != nil {
+		if os.IsPermission(err) || errors.Is(err, syscall.EROFS) {
+			return false
+		}
+		b.Panic(err)
+		return false
+	}
+	f.Close()
+	if fi.IsDir() {
+		os.Remove(f.Name())
+	}
+	return true
+}
+
+// Chmod is os.Chmod, but with errors handled by this instance of Bsh
+func (b *Bsh) Chmod(path string, mode fs.FileMode) {
+	b.Verbosef("Chmod: %s to 0o%o", path, mode)
+	if err := os.Chmod(path, mode); err != nil {
+		b.Panic(err)
+	}
+}
+
 // Stat is os.Stat, but with errors handled by this instance of Bsh
 func (b *Bsh) Stat(path string) fs.FileInfo {
 	b.Verbosef("Stat: %s", path)
@@ -127,6 +204,37 @@ func (b *Bsh) Stat(path string) fs.FileInfo {
 	return fi
 }
 
+// FileDetails is a simplified summary of a path, as returned by Info.
+type FileDetails struct {
+	Exists    bool
+	IsDir     bool
+	IsSymlink bool
+	Size      int64
+	Mode      os.FileMode
+	ModTime   time.Time
+}
+
+// Info returns details about path from a single call to os.Lstat. If path is a symlink, the
+// details describe the symlink itself, not what it points to. If path doesn't exist, a zero value
+// FileDetails is returned (so Exists is false). Other errors are handled by this instance of Bsh.
+func (b *Bsh) Info(path string) FileDetails {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			b.Panic(err)
+		}
+		return FileDetails{}
+	}
+	return FileDetails{
+		Exists:    true,
+		IsDir:     fi.IsDir(),
+		IsSymlink: fi.Mode()&os.ModeSymlink != 0,
+		Size:      fi.Size(),
+		Mode:      fi.Mode(),
+		ModTime:   fi.ModTime(),
+	}
+}
+
 // InDir saves the cwd, creates the given path (if needed), cds into the
 // given path, executes the given func, then restores the previous cwd.
 func (b *Bsh) InDir(path string, fn func()) {
@@ -175,3 +283,228 @@ func (b *Bsh) IsExeInPath(file string) bool {
 	}
 	return len(path) > 0
 }
+
+// FirstExeInPath returns the first of the given executable names that can be found in the PATH
+// environment var, and true. If none of them are found, it returns an empty string and false.
+func (b *Bsh) FirstExeInPath(candidates ...string) (string, bool) {
+	for _, file := range candidates {
+		if b.IsExeInPath(file) {
+			return file, true
+		}
+	}
+	return "", false
+}
+
+// MustFirstExeInPath is FirstExeInPath, but if none of the candidates are found, the error
+// is handled by this instance of Bsh.
+func (b *Bsh) MustFirstExeInPath(candidates ...string) string {
+	file, ok := b.FirstExeInPath(candidates...)
+	if !ok {
+		b.Panic(fmt.Errorf("none of %v were found in PATH", candidates))
+	}
+	return file
+}
+
+// Glob is filepath.Glob, but with errors handled by this instance of Bsh. Returns the paths that
+// match the given pattern (see filepath.Match for the pattern syntax), which may be none.
+func (b *Bsh) Glob(pattern string) []string {
+	matches, err := b.GlobErr(pattern)
+	if err != nil {
+		b.Panic(err)
+	}
+	return matches
+}
+
+// GlobErr is Glob, but the error is returned instead of being handled by this instance of Bsh.
+func (b *Bsh) GlobErr(pattern string) ([]string, error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	b.Verbosef("Glob: %s (%d match(es))", pattern, len(matches))
+	return matches, nil
+}
+
+// GlobTree walks every file and folder inside root (recursively), and returns the paths of those
+// whose name matches pattern (see filepath.Match for the pattern syntax).
+func (b *Bsh) GlobTree(root, pattern string) []string {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		b.Panic(err)
+		return nil
+	}
+	matches := make([]string, 0, 64)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		if ok, _ := filepath.Match(pattern, d.Name()); ok {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	if err != nil {
+		b.Panic(err)
+	}
+	b.Verbosef("GlobTree: %s in %s (%d match(es))", pattern, root, len(matches))
+	return matches
+}
+
+// Walk calls fn for root, and for every file and folder inside it (recursively), in lexical
+// order. If fn returns filepath.SkipDir for a folder, that folder's contents are skipped.
+// Any other error returned from fn stops the walk, and is handled by this instance of Bsh, as are
+// any errors encountered while walking.
+func (b *Bsh) Walk(root string, fn func(path string, info fs.FileInfo) error) {
+	b.Verbosef("Walk: %s", root)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(path, info)
+	})
+	if err != nil {
+		b.Panic(err)
+	}
+}
+
+// ForEachGlob calls fn for each path that matches the given pattern (see filepath.Match for
+// the pattern syntax). If nothing matches, fn is never called.
+func (b *Bsh) ForEachGlob(pattern string, fn func(path string)) {
+	for _, path := range b.Glob(pattern) {
+		fn(path)
+	}
+}
+
+// FindExesInPath returns the names of all executables in the folders listed in the PATH
+// environment var whose names match pattern (see filepath.Match for the pattern syntax).
+// On Windows, only .exe files are considered, and the pattern may omit the .exe extension.
+// Names are returned in PATH order, without duplicates.
+func (b *Bsh) FindExesInPath(pattern string) []string {
+	seen := make(map[string]bool)
+	found := make([]string, 0, 8)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if len(dir) == 0 {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			// PATH often contains folders that don't exist or can't be read
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if seen[name] || !isExecutable(dir, entry) || !matchExeName(pattern, name) {
+				continue
+			}
+			seen[name] = true
+			found = append(found, name)
+		}
+	}
+	b.Verbosef("FindExesInPath: %s (%d match(es))", pattern, len(found))
+	return found
+}
+
+func isExecutable(dir string, entry fs.DirEntry) bool {
+	if entry.IsDir() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(entry.Name()), ".exe")
+	}
+	// use Stat instead of entry.Info so that symlinks are followed
+	fi, err := os.Stat(filepath.Join(dir, entry.Name()))
+	if err != nil {
+		return false
+	}
+	return fi.Mode().IsRegular() && fi.Mode().Perm()&0111 != 0
+}
+
+func matchExeName(pattern, name string) bool {
+	if ok, _ := filepath.Match(pattern, name); ok {
+		return true
+	}
+	if runtime.GOOS == "windows" {
+		ok, _ := filepath.Match(pattern, name[:len(name)-len(filepath.Ext(name))])
+		return ok
+	}
+	return false
+}
+
+// ExpandHome replaces a leading "~" in path with the current user's home folder.
+// Paths that refer to other users' home folders (eg "~bob/") are returned unchanged.
+func (b *Bsh) ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		b.Panic(err)
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// CleanPath trims whitespace, expands a leading "~", and converts path to a clean, absolute path.
+// If path is empty, or if mustExist is true and path doesn't exist, the error is handled by this
+// instance of Bsh.
+func (b *Bsh) CleanPath(path string, mustExist bool) string {
+	trimmed := strings.TrimSpace(path)
+	if len(trimmed) == 0 {
+		b.Panic(fmt.Errorf("path is empty"))
+		return ""
+	}
+	abs, err := filepath.Abs(b.ExpandHome(trimmed))
+	if err != nil {
+		b.Panic(err)
+		return ""
+	}
+	if mustExist && !b.Exists(abs) {
+		b.Panic(fmt.Errorf("path %s does not exist", path))
+	}
+	return abs
+}
+
+// CommonRoot returns the deepest folder that contains all of the given paths, after converting
+// each to an absolute path. If there is only one path, it is returned (as an absolute path).
+// Returns an empty string if no paths are passed, or if the paths share no common root (eg
+// they are on different drives on Windows).
+func (b *Bsh) CommonRoot(paths ...string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+	root, err := filepath.Abs(paths[0])
+	if err != nil {
+		b.Panic(err)
+		return ""
+	}
+	for _, path := range paths[1:] {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			b.Panic(err)
+			return ""
+		}
+		for !isWithin(root, abs) {
+			parent := filepath.Dir(root)
+			if parent == root {
+				return ""
+			}
+			root = parent
+		}
+	}
+	return root
+}
+
+// isWithin returns true if path is root, or is inside of root. Both must be clean absolute paths.
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
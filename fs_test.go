@@ -0,0 +1,150 @@
+package bsh
+
+import (
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCommonRoot(t *testing.T) {
+	b := Bsh{}
+	abs := func(path string) string {
+		p, err := filepath.Abs(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	cases := []struct {
+		Paths    []string
+		Expected string
+	}{
+		{nil, ""},
+		{[]string{"a/b/c.txt"}, abs("a/b/c.txt")},
+		{[]string{"a/b/c.txt", "a/b/d.txt"}, abs("a/b")},
+		{[]string{"a/b/c.txt", "a/bb/d.txt", "a/e"}, abs("a")},
+		{[]string{"a/b", "a/b/c/d"}, abs("a/b")},
+		{[]string{"a/b", "x/y"}, abs(".")},
+	}
+
+	for _, tc := range cases {
+		actual := b.CommonRoot(tc.Paths...)
+		if actual != tc.Expected {
+			t.Errorf(`CommonRoot(%v): expected "%s", got "%s"`, tc.Paths, tc.Expected, actual)
+		}
+	}
+}
+
+func TestInfo(t *testing.T) {
+	ensureLocalFolder(t)
+	b := Bsh{}
+	b.Write("local/info_test.txt", "12345")
+
+	info := b.Info("local/info_test.txt")
+	if !info.Exists || info.IsDir || info.IsSymlink || info.Size != 5 || info.ModTime.IsZero() {
+		t.Errorf("unexpected details for file: %+v", info)
+	}
+	if info := b.Info("local"); !info.Exists || !info.IsDir {
+		t.Errorf("unexpected details for folder: %+v", info)
+	}
+	if info := b.Info("local/info_test_missing.txt"); info != (FileDetails{}) {
+		t.Errorf("expected zero value for missing path, but got %+v", info)
+	}
+}
+
+func TestRemoveGlob(t *testing.T) {
+	ensureLocalFolder(t)
+	b := Bsh{}
+	b.RemoveAll("local/remove_test")
+	b.Touch("local/remove_test/a.tmp")
+	b.Touch("local/remove_test/b.tmp")
+	b.Touch("local/remove_test/c.txt")
+	b.Touch("local/remove_test/d/e.txt")
+
+	b.RemoveGlob("local/remove_test/*.tmp")
+	if b.Exists("local/remove_test/a.tmp") || b.Exists("local/remove_test/b.tmp") {
+		t.Errorf("expected RemoveGlob to remove *.tmp files")
+	}
+	if !b.Exists("local/remove_test/c.txt") {
+		t.Errorf("expected RemoveGlob to leave c.txt alone")
+	}
+
+	b.RemoveAllMulti("local/remove_test/c.txt", "local/remove_test/d", "local/remove_test/missing")
+	if b.Exists("local/remove_test/c.txt") || b.Exists("local/remove_test/d") {
+		t.Errorf("expected RemoveAllMulti to remove each path")
+	}
+}
+
+func TestGlobTree(t *testing.T) {
+	ensureLocalFolder(t)
+	b := Bsh{}
+	b.RemoveAll("local/glob_test")
+	// not .go files, since anything in local/ would be built by "go test ./..."
+	b.Touch("local/glob_test/a.md")
+	b.Touch("local/glob_test/b.txt")
+	b.Touch("local/glob_test/sub/c.md")
+
+	if actual := b.Glob("local/glob_test/*.md"); len(actual) != 1 {
+		t.Errorf("expected Glob to find 1 match, but found %v", actual)
+	}
+
+	actual := b.GlobTree("local/glob_test", "*.md")
+	expected := []string{filepath.Join("local/glob_test", "a.md"), filepath.Join("local/glob_test", "sub", "c.md")}
+	if len(actual) != 2 || actual[0] != expected[0] || actual[1] != expected[1] {
+		t.Errorf("expected GlobTree to find %v, but found %v", expected, actual)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	ensureLocalFolder(t)
+	b := Bsh{}
+	b.RemoveAll("local/walk_test")
+	b.MkdirAll("local/walk_test")
+	b.Write("local/walk_test/a.txt", "12")
+	b.MkdirAll("local/walk_test/keep")
+	b.Write("local/walk_test/keep/b.txt", "345")
+	b.MkdirAll("local/walk_test/skip")
+	b.Write("local/walk_test/skip/c.txt", "6789")
+
+	var total int64
+	b.Walk("local/walk_test", func(path string, info fs.FileInfo) error {
+		if info.IsDir() && info.Name() == "skip" {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() {
+			total += info.Size()
+		}
+		return nil
+	})
+	if total != 5 {
+		t.Errorf("expected Walk to visit 5 bytes of files outside the skipped folder, but got %d", total)
+	}
+}
+
+func TestSymlinkForce(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symlinks on windows may require elevated privileges")
+	}
+	ensureLocalFolder(t)
+	b := Bsh{}
+	b.RemoveAll("local/symlink_force_test")
+	b.MkdirAll("local/symlink_force_test")
+	b.Write("local/symlink_force_test/a.txt", "a")
+	b.Write("local/symlink_force_test/b.txt", "b")
+
+	b.SymlinkForce("a.txt", "local/symlink_force_test/link")
+	b.SymlinkForce("b.txt", "local/symlink_force_test/link")
+	if actual := b.Read("local/symlink_force_test/link"); actual != "b" {
+		t.Errorf(`expected link to point to b.txt, but read "%s"`, actual)
+	}
+
+	err := b.Try(func() { b.SymlinkForce("b.txt", "local/symlink_force_test/a.txt") })
+	if err == nil {
+		t.Errorf("expected an error when linkPath is a regular file")
+	}
+	if actual := b.Read("local/symlink_force_test/a.txt"); actual != "a" {
+		t.Errorf(`expected a.txt to be left alone, but read "%s"`, actual)
+	}
+}
@@ -1,14 +1,17 @@
 package bsh
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"syscall"
 )
 
-// Copy attempts to open file at src and create/overwrite new file at dst, then copy the contents.
-// If src does not exist, Copy returns false, otherwise it returns true. Other errors will panic.
+// Copy attempts to open file at src and create/overwrite new file at dst, then copy the contents
+// and file mode. If src does not exist, Copy returns false, otherwise it returns true. Other errors will panic.
 func (b *Bsh) Copy(src, dst string) bool {
 	err := b.copyImpl(src, dst)
 	if err != nil {
@@ -20,8 +23,8 @@ func (b *Bsh) Copy(src, dst string) bool {
 	return true
 }
 
-// MustCopy attempts to open file at src and create/overwrite new file at dst, then copy the contents.
-// Any error in this process will panic.
+// MustCopy attempts to open file at src and create/overwrite new file at dst, then copy the contents
+// and file mode. Any error in this process will panic.
 func (b *Bsh) MustCopy(src, dst string) {
 	err := b.copyImpl(src, dst)
 	if err != nil {
@@ -33,6 +36,28 @@ func (b *Bsh) MustCopy(src, dst string) {
 // in that order. This ensures copying into a subfolder of src doesn't recurse forever.
 // Src and dst must both exist and be folders. Duplicates in dst will be overwritten.
 func (b *Bsh) CopyContents(src, dst string) {
+	b.copyContents(src, dst, false)
+}
+
+// CopyContentsPreserve is CopyContents, but the copied files and folders also keep the access
+// and modification times of the originals (see CopyPreserve).
+func (b *Bsh) CopyContentsPreserve(src, dst string) {
+	b.copyContents(src, dst, true)
+}
+
+// CopyPreserve is MustCopy, but dst also keeps the access and modification times of src, so
+// tools that compare timestamps don't see the copy as newly modified.
+func (b *Bsh) CopyPreserve(src, dst string) {
+	b.MustCopy(src, dst)
+	if b.DryRun {
+		return
+	}
+	if err := copyTimes(src, dst); err != nil {
+		b.Panic(err)
+	}
+}
+
+func (b *Bsh) copyContents(src, dst string, preserveTimes bool) {
 	if !b.IsDir(src) {
 		b.Panic(fmt.Errorf("src %s is not a folder or does not exist", src))
 	}
@@ -43,12 +68,51 @@ func (b *Bsh) CopyContents(src, dst string) {
 	toCopy := make([]copyEntry, 0, 1024)
 	toCopy = b.buildCopyList(src, dst, toCopy)
 	for _, entry := range toCopy {
-		if entry.isDir {
+		switch {
+		case entry.isDir && b.DryRun:
+			b.dryRunf("would create folder %s", entry.dstPath)
+		case entry.isDir:
 			b.MkdirAll(entry.dstPath)
-		} else {
+		case preserveTimes:
+			b.CopyPreserve(entry.srcPath, entry.dstPath)
+		default:
 			b.MustCopy(entry.srcPath, entry.dstPath)
 		}
 	}
+
+	if !preserveTimes || b.DryRun {
+		return
+	}
+	// adding files to a folder changes its modification time, so folders are done last, and
+	// deepest first
+	for i := len(toCopy) - 1; i >= 0; i-- {
+		if toCopy[i].isDir {
+			if err := copyTimes(toCopy[i].srcPath, toCopy[i].dstPath); err != nil {
+				b.Panic(err)
+			}
+		}
+	}
+}
+
+// SamePath returns true if both paths refer to the same file or folder, even if the paths are
+// spelled differently (eg relative vs absolute, or via a symlink). Returns false if either path
+// does not exist.
+func (b *Bsh) SamePath(path1, path2 string) bool {
+	fi1, err := os.Stat(path1)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			b.Panic(err)
+		}
+		return false
+	}
+	fi2, err := os.Stat(path2)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			b.Panic(err)
+		}
+		return false
+	}
+	return os.SameFile(fi1, fi2)
 }
 
 type copyEntry struct {
@@ -75,6 +139,9 @@ func (b *Bsh) buildCopyList(src, dst string, files []copyEntry) []copyEntry {
 
 func (b *Bsh) copyImpl(src, dst string) error {
 	b.Verbosef("Copy: %s => %s", src, dst)
+	if b.DryRun {
+		return b.dryRunCopy(src, dst)
+	}
 	sf, err := os.Open(src)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -93,6 +160,18 @@ func (b *Bsh) copyImpl(src, dst string) error {
 	}
 	srcSize := info.Size()
 
+	if dstInfo, err := os.Stat(dst); err == nil {
+		if os.SameFile(info, dstInfo) {
+			return fmt.Errorf("src %s and dst %s are the same file", src, dst)
+		}
+		// an earlier copy of a read-only src leaves dst read-only, so make it writable to overwrite it
+		if perm := dstInfo.Mode().Perm(); perm&0200 == 0 {
+			if err := os.Chmod(dst, perm|0200); err != nil {
+				return fmt.Errorf("error making dst %s writable: %w", dst, err)
+			}
+		}
+	}
+
 	df, err := os.Create(dst)
 	if err != nil {
 		return fmt.Errorf("error creating dst %s: %w", dst, err)
@@ -106,5 +185,260 @@ func (b *Bsh) copyImpl(src, dst string) error {
 	if dstSize != srcSize {
 		return fmt.Errorf("%s has %d byte(s), but the copy %s only has %d byte(s)", src, srcSize, dst, dstSize)
 	}
+	// preserve the mode (eg so executables stay executable)
+	if err := df.Chmod(info.Mode().Perm()); err != nil {
+		return fmt.Errorf("error setting mode of dst %s: %w", dst, err)
+	}
+	return nil
+}
+
+// Move renames src to dst. If they are on different filesystems (where a rename isn't possible),
+// src is copied to dst, then src is removed. Works for both files and folders.
+func (b *Bsh) Move(src, dst string) {
+	if err := b.MoveErr(src, dst); err != nil {
+		b.Panic(err)
+	}
+}
+
+// MoveErr is Move, but the error is returned instead of being handled by this instance of Bsh.
+func (b *Bsh) MoveErr(src, dst string) error {
+	b.Verbosef("Move: %s => %s", src, dst)
+	if b.DryRun {
+		b.dryRunf("move %s to %s", src, dst)
+		return nil
+	}
+	err := os.Rename(src, dst)
+	if err == nil || !isCrossDevice(err) {
+		return err
+	}
+	b.Verbosef("Rename across filesystems not possible, falling back to copy and remove")
+	if err := b.copyTree(src, dst); err != nil {
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// copyTree copies src to dst, where src can be a file or a folder. Symlinks are recreated as
+// symlinks (pointing to the same place), rather than copying what they point to, just as a rename
+// would leave them.
+func (b *Bsh) copyTree(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return copySymlink(src, dst)
+	}
+	if !info.IsDir() {
+		return b.copyImpl(src, dst)
+	}
+	files, err := listFolder(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dst, os.ModePerm); err != nil {
+		return err
+	}
+	for _, file := range files {
+		srcPath := filepath.Join(src, filepath.FromSlash(file))
+		dstPath := filepath.Join(dst, filepath.FromSlash(file))
+		fi, err := os.Lstat(srcPath)
+		if err != nil {
+			return err
+		}
+		switch {
+		case fi.Mode()&os.ModeSymlink != 0:
+			err = copySymlink(srcPath, dstPath)
+		case fi.IsDir():
+			err = os.MkdirAll(dstPath, os.ModePerm)
+		default:
+			err = b.copyImpl(srcPath, dstPath)
+		}
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// copySymlink creates a symlink at dst that points to wherever the symlink at src points
+func copySymlink(src, dst string) error {
+	link, err := os.Readlink(src)
+	if err != nil {
+		return err
+	}
+	return os.Symlink(link, dst)
+}
+
+// isCrossDevice returns true if err is from trying to rename a file across filesystems
+func isCrossDevice(err error) bool {
+	var le *os.LinkError
+	if !errors.As(err, &le) {
+		return false
+	}
+	if errors.Is(le.Err, syscall.EXDEV) {
+		return true
+	}
+	// ERROR_NOT_SAME_DEVICE
+	return runtime.GOOS == "windows" && le.Err == syscall.Errno(17)
+}
+
+// Link is os.Link, but with errors handled by this instance of Bsh
+func (b *Bsh) Link(oldname, newname string) {
+	b.Verbosef("Link: %s => %s", oldname, newname)
+	if b.DryRun {
+		b.dryRunf("would link %s to %s", newname, oldname)
+		return
+	}
+	if err := os.Link(oldname, newname); err != nil {
+		b.Panic(err)
+	}
+}
+
+// LinkOrCopy attempts to create a hard link at newname that points to oldname.
+// If the link fails (eg across filesystems, or on a filesystem that doesn't support
+// hard links), it falls back to copying the file contents instead.
+func (b *Bsh) LinkOrCopy(oldname, newname string) {
+	b.Verbosef("LinkOrCopy: %s => %s", oldname, newname)
+	if b.DryRun {
+		b.dryRunf("would link (or copy) %s to %s", newname, oldname)
+		return
+	}
+	if err := os.Link(oldname, newname); err != nil {
+		b.Verbosef("Link failed, falling back to Copy: %v", err)
+		b.MustCopy(oldname, newname)
+	}
+}
+
+// SplitFile copies the contents of src into a sequence of files named src.part0, src.part1, etc,
+// each of which is at most chunkSize bytes. It returns the paths of the created part files.
+func (b *Bsh) SplitFile(src string, chunkSize int64) []string {
+	b.Verbosef("SplitFile: %s into chunks of %d byte(s)", src, chunkSize)
+	parts, err := splitFile(src, chunkSize)
+	if err != nil {
+		b.Panic(err)
+	}
+	return parts
+}
+
+// JoinFiles creates/overwrites dst, then copies the contents of each part into it, in order.
+func (b *Bsh) JoinFiles(dst string, parts ...string) {
+	b.Verbosef("JoinFiles: %d part(s) => %s", len(parts), dst)
+	if err := concatFiles(dst, nil, parts...); err != nil {
+		b.Panic(err)
+	}
+}
+
+func splitFile(src string, chunkSize int64) ([]string, error) {
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("invalid chunk size %d", chunkSize)
+	}
+	sf, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer sf.Close()
+
+	info, err := sf.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]string, 0, info.Size()/chunkSize+1)
+	for i := 0; ; i++ {
+		part := fmt.Sprintf("%s.part%d", src, i)
+		n, err := copyChunk(part, sf, chunkSize)
+		if err != nil {
+			return parts, err
+		}
+		if n == 0 && i > 0 {
+			os.Remove(part)
+			break
+		}
+		parts = append(parts, part)
+		if n < chunkSize {
+			break
+		}
+	}
+	return parts, nil
+}
+
+// copyChunk creates dst and copies up to n bytes from r into it
+func copyChunk(dst string, r io.Reader, n int64) (int64, error) {
+	df, err := os.Create(dst)
+	if err != nil {
+		return 0, err
+	}
+	defer df.Close()
+	written, err := io.CopyN(df, r, n)
+	if err == io.EOF {
+		err = nil
+	}
+	return written, err
+}
+
+// concatFiles creates dst, then copies each src into it, with sep written between each src
+func concatFiles(dst string, sep []byte, srcs ...string) error {
+	df, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer df.Close()
+	for i, src := range srcs {
+		if i > 0 && len(sep) > 0 {
+			if _, err := df.Write(sep); err != nil {
+				return err
+			}
+		}
+		if err := copyFileTo(df, src); err != nil {
+			return err
+		}
+	}
+	return df.Close()
+}
+
+// Concat creates/overwrites dst, then copies the contents of each src into it, in order.
+func (b *Bsh) Concat(dst string, srcs ...string) {
+	if err := b.ConcatSepErr(dst, "", srcs...); err != nil {
+		b.Panic(err)
+	}
+}
+
+// ConcatErr is Concat, but the error is returned instead of being handled by this instance of Bsh.
+func (b *Bsh) ConcatErr(dst string, srcs ...string) error {
+	return b.ConcatSepErr(dst, "", srcs...)
+}
+
+// ConcatSep is like Concat, but writes sep between the contents of each src.
+func (b *Bsh) ConcatSep(dst, sep string, srcs ...string) {
+	if err := b.ConcatSepErr(dst, sep, srcs...); err != nil {
+		b.Panic(err)
+	}
+}
+
+// ConcatSepErr is ConcatSep, but the error is returned instead of being handled by this instance
+// of Bsh.
+func (b *Bsh) ConcatSepErr(dst, sep string, srcs ...string) error {
+	b.Verbosef("Concat: %d file(s) => %s", len(srcs), dst)
+	if b.DryRun {
+		b.dryRunf("would concatenate %d file(s) into %s", len(srcs), dst)
+		return nil
+	}
+	return concatFiles(dst, []byte(sep), srcs...)
+}
+
+// CopyTimes sets the access and modification times of dst to match those of src.
+func (b *Bsh) CopyTimes(src, dst string) {
+	b.Verbosef("CopyTimes: %s => %s", src, dst)
+	if err := copyTimes(src, dst); err != nil {
+		b.Panic(err)
+	}
+}
+
+func copyTimes(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(dst, accessTime(info), info.ModTime())
+}
@@ -1,8 +1,13 @@
 package bsh
 
 import (
+	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestCopyContents(t *testing.T) {
@@ -35,3 +40,175 @@ func TestCopyContents(t *testing.T) {
 		}
 	}
 }
+
+func TestSplitAndJoinFiles(t *testing.T) {
+	b := Bsh{}
+	b.MkdirAll("local/split_test")
+	b.InDir("local/split_test", func() {
+		contents := strings.Repeat("0123456789", 25)
+		b.Write("big.txt", contents)
+
+		parts := b.SplitFile("big.txt", 100)
+		if len(parts) != 3 {
+			t.Fatalf("expected 3 parts, but got %d: %v", len(parts), parts)
+		}
+
+		b.JoinFiles("joined.txt", parts...)
+		if actual := b.Read("joined.txt"); actual != contents {
+			t.Errorf(`expected joined file to match original, but got "%s"`, actual)
+		}
+	})
+}
+
+func TestCopyOntoItself(t *testing.T) {
+	b := Bsh{}
+	b.MkdirAll("local/copy_self_test")
+	b.InDir("local/copy_self_test", func() {
+		b.Write("self.txt", "precious")
+		if !b.SamePath("self.txt", "../copy_self_test/self.txt") {
+			t.Fatal("expected SamePath to match differently spelled paths")
+		}
+
+		var err error
+		b.SetErrorHandler(func(e error) { err = e })
+		b.MustCopy("self.txt", "./self.txt")
+		b.SetErrorHandler(nil)
+
+		if err == nil {
+			t.Error("expected an error when copying a file onto itself")
+		}
+		if actual := b.Read("self.txt"); actual != "precious" {
+			t.Errorf(`expected file contents to be unchanged, but got "%s"`, actual)
+		}
+	})
+}
+
+func TestCopyTimes(t *testing.T) {
+	b := Bsh{}
+	b.MkdirAll("local/copy_times_test")
+	b.InDir("local/copy_times_test", func() {
+		b.Write("src.txt", "src")
+		b.Write("dst.txt", "dst")
+		past := time.Now().Add(-48 * time.Hour)
+		b.Must(os.Chtimes("src.txt", past, past))
+
+		b.CopyTimes("src.txt", "dst.txt")
+
+		diff := b.Stat("dst.txt").ModTime().Sub(b.Stat("src.txt").ModTime())
+		if diff < -time.Second || diff > time.Second {
+			t.Errorf("expected mod times to match, but they differ by %v", diff)
+		}
+	})
+}
+
+func TestMove(t *testing.T) {
+	ensureLocalFolder(t)
+	b := Bsh{}
+	b.RemoveAll("local/move_test")
+	b.MkdirAll("local/move_test/src/sub")
+	b.Write("local/move_test/src/a.txt", "alpha")
+	b.Write("local/move_test/src/sub/b.txt", "bravo")
+
+	b.Move("local/move_test/src", "local/move_test/dst")
+	if b.Exists("local/move_test/src") || b.Read("local/move_test/dst/sub/b.txt") != "bravo" {
+		t.Fatal("expected Move to rename src to dst")
+	}
+
+	// the fallback used when a rename crosses filesystems
+	if err := b.copyTree("local/move_test/dst", "local/move_test/copy"); err != nil {
+		t.Fatal(err)
+	}
+	if b.Read("local/move_test/copy/a.txt") != "alpha" || b.Read("local/move_test/copy/sub/b.txt") != "bravo" {
+		t.Error("expected copyTree to copy every file")
+	}
+	if !isCrossDevice(&os.LinkError{Op: "rename", Old: "a", New: "b", Err: syscall.EXDEV}) {
+		t.Error("expected EXDEV to be detected as a cross device error")
+	}
+}
+
+func TestCopyTreeSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symlinks requires extra privileges on windows")
+	}
+	ensureLocalFolder(t)
+	b := Bsh{}
+	b.RemoveAll("local/copytree_links")
+	b.MkdirAll("local/copytree_links/src/real")
+	b.Write("local/copytree_links/src/real/a.txt", "alpha")
+	b.Must(os.Symlink("real", "local/copytree_links/src/alias"))
+	b.Must(os.Symlink("real/a.txt", "local/copytree_links/src/a_link.txt"))
+
+	if err := b.copyTree("local/copytree_links/src", "local/copytree_links/dst"); err != nil {
+		t.Fatal(err)
+	}
+	for link, expected := range map[string]string{"alias": "real", "a_link.txt": "real/a.txt"} {
+		actual, err := os.Readlink(filepath.Join("local/copytree_links/dst", link))
+		if err != nil || actual != expected {
+			t.Errorf(`expected %s to be a symlink to "%s", but got "%s" (%v)`, link, expected, actual, err)
+		}
+	}
+	if actual := b.Read("local/copytree_links/dst/alias/a.txt"); actual != "alpha" {
+		t.Errorf(`expected "alpha" through the copied symlink, but got "%s"`, actual)
+	}
+}
+
+func TestCopyPreservesMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("windows has no executable bit")
+	}
+	ensureLocalFolder(t)
+	b := Bsh{}
+	b.Write("local/copy_mode_src.sh", "#!/bin/sh\n")
+	b.Chmod("local/copy_mode_src.sh", 0755)
+	b.RemoveAll("local/copy_mode_dst.sh")
+
+	b.MustCopy("local/copy_mode_src.sh", "local/copy_mode_dst.sh")
+	if mode := b.Stat("local/copy_mode_dst.sh").Mode().Perm(); mode != 0755 {
+		t.Errorf("expected copy to have mode 0755, but it has 0%o", mode)
+	}
+}
+
+func TestCopyReadOnlyTwice(t *testing.T) {
+	ensureLocalFolder(t)
+	b := Bsh{}
+	b.RemoveAll("local/copy_readonly_dst.txt")
+	b.Write("local/copy_readonly_src.txt", "first")
+	b.Chmod("local/copy_readonly_src.txt", 0444)
+	defer b.Chmod("local/copy_readonly_src.txt", 0644)
+
+	b.MustCopy("local/copy_readonly_src.txt", "local/copy_readonly_dst.txt")
+	b.Chmod("local/copy_readonly_src.txt", 0644)
+	b.Write("local/copy_readonly_src.txt", "second")
+	b.Chmod("local/copy_readonly_src.txt", 0444)
+	b.MustCopy("local/copy_readonly_src.txt", "local/copy_readonly_dst.txt")
+
+	if actual := b.Read("local/copy_readonly_dst.txt"); actual != "second" {
+		t.Errorf(`expected "second", but got "%s"`, actual)
+	}
+	if runtime.GOOS != "windows" {
+		if mode := b.Stat("local/copy_readonly_dst.txt").Mode().Perm(); mode != 0444 {
+			t.Errorf("expected copy to have mode 0444, but it has 0%o", mode)
+		}
+	}
+}
+
+func TestCopyContentsPreserve(t *testing.T) {
+	ensureLocalFolder(t)
+	b := Bsh{}
+	b.RemoveAll("local/copy_preserve_test")
+	b.MkdirAll("local/copy_preserve_test/src/sub")
+	b.MkdirAll("local/copy_preserve_test/dst")
+	b.Write("local/copy_preserve_test/src/sub/a.txt", "alpha")
+	past := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	b.Must(os.Chtimes("local/copy_preserve_test/src/sub/a.txt", past, past))
+	b.Must(os.Chtimes("local/copy_preserve_test/src/sub", past, past))
+
+	b.CopyContentsPreserve("local/copy_preserve_test/src", "local/copy_preserve_test/dst")
+
+	for _, path := range []string{"sub/a.txt", "sub"} {
+		actual := b.Stat(filepath.Join("local/copy_preserve_test/dst", path)).ModTime()
+		if !actual.Equal(past) {
+			t.Errorf("expected %s to have mod time %v, but it has %v", path, past, actual)
+		}
+	}
+}
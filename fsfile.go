@@ -1,10 +1,12 @@
 package bsh
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
 )
 
@@ -22,6 +24,14 @@ func (b *Bsh) Writef(path string, format string, args ...interface{}) {
 	}
 }
 
+// WriteValue writes value, with leading and trailing whitespace trimmed, followed by a single
+// newline. This is the counterpart to ReadValue.
+func (b *Bsh) WriteValue(path, value string) {
+	if err := b.writeImpl(path, strings.TrimSpace(value)+"\n", nil, false); err != nil {
+		b.Panic(err)
+	}
+}
+
 func (b *Bsh) WriteErr(path string, contents string) error {
 	return b.writeImpl(path, contents, nil, false)
 }
@@ -36,6 +46,31 @@ func (b *Bsh) WriteBytesErr(path string, data []byte) error {
 	return b.writeImpl(path, "", data, false)
 }
 
+// WriteReversible is Write, but it also returns a func that restores the file to how it was
+// before the write (rewriting the previous contents, or removing the file if it didn't exist).
+func (b *Bsh) WriteReversible(path string, contents string) (rollback func()) {
+	prev, err := os.ReadFile(path)
+	existed := true
+	if err != nil {
+		if !os.IsNotExist(err) {
+			b.Panic(err)
+		}
+		existed = false
+	}
+	b.Write(path, contents)
+	return func() {
+		b.Verbosef("Rollback write to file: %s", path)
+		switch {
+		case existed:
+			b.WriteBytes(path, prev)
+		case b.DryRun:
+			b.dryRunf("would remove %s", path)
+		default:
+			b.Remove(path)
+		}
+	}
+}
+
 // Append file
 
 func (b *Bsh) Append(path string, contents string) {
@@ -68,6 +103,12 @@ func (b *Bsh) writeImpl(path string, str string, data []byte, append bool) error
 	if len(str) > 0 && len(data) > 0 {
 		return fmt.Errorf("this should never happen: writeImpl has both string and []byte")
 	}
+	if b.DryRun {
+		if len(str) > 0 {
+			data = []byte(str)
+		}
+		return b.dryRunWrite(path, data, append)
+	}
 	var f *os.File
 	var err error
 	if append {
@@ -112,6 +153,25 @@ func (b *Bsh) ReadErr(path string) (string, error) {
 	return string(data), nil
 }
 
+// ReadRedacted is Read, but with any secrets added via PushEchoFilter replaced with asterisks,
+// so that the contents are safe to echo.
+func (b *Bsh) ReadRedacted(path string) string {
+	return b.applyEchoFilters(b.Read(path))
+}
+
+// ReadNoBOM is Read, but with any leading UTF-8 byte order mark removed.
+func (b *Bsh) ReadNoBOM(path string) string {
+	return strings.TrimPrefix(b.Read(path), utf8BOM)
+}
+
+const utf8BOM = "\xEF\xBB\xBF"
+
+// ReadValue is Read, but with leading and trailing whitespace trimmed. This is useful for files
+// that contain a single value (eg a version number or a token).
+func (b *Bsh) ReadValue(path string) string {
+	return strings.TrimSpace(b.Read(path))
+}
+
 func (b *Bsh) ReadFile(path string) []byte {
 	b.Verbosef("Read from file: %s", path)
 	data, err := os.ReadFile(path)
@@ -120,3 +180,136 @@ func (b *Bsh) ReadFile(path string) []byte {
 	}
 	return data
 }
+
+// ReadHead returns up to the first n lines of the file at path (fewer if the file is shorter).
+// Only the requested lines are read, so this is safe to use on very large files.
+func (b *Bsh) ReadHead(path string, n int) []string {
+	b.Verbosef("Read head (%d lines) from file: %s", n, path)
+	if n <= 0 {
+		return []string{}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		b.Panic(err)
+	}
+	defer f.Close()
+
+	// bufio.Reader (unlike bufio.Scanner) has no limit on the length of a line
+	lines := make([]string, 0, n)
+	r := bufio.NewReader(f)
+	for len(lines) < n {
+		line, err := r.ReadString('\n')
+		if len(line) > 0 {
+			lines = append(lines, strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			b.Panic(err)
+			break
+		}
+	}
+	return lines
+}
+
+// Line endings
+
+// ToUnixLineEndings converts all CRLF (and lone CR) line endings in the file at path to LF.
+// The file is only rewritten if something changed, and files that appear to be binary (ie
+// contain a NUL byte) are left alone. Returns true if the file was changed.
+func (b *Bsh) ToUnixLineEndings(path string) bool {
+	b.Verbosef("ToUnixLineEndings: %s", path)
+	return b.convertLineEndings(path, func(data []byte) []byte {
+		data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+		return bytes.ReplaceAll(data, []byte("\r"), []byte("\n"))
+	})
+}
+
+// ToWindowsLineEndings converts all LF (and lone CR) line endings in the file at path to CRLF.
+// The file is only rewritten if something changed, and files that appear to be binary (ie
+// contain a NUL byte) are left alone. Returns true if the file was changed.
+func (b *Bsh) ToWindowsLineEndings(path string) bool {
+	b.Verbosef("ToWindowsLineEndings: %s", path)
+	return b.convertLineEndings(path, func(data []byte) []byte {
+		data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+		data = bytes.ReplaceAll(data, []byte("\r"), []byte("\n"))
+		return bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+	})
+}
+
+func (b *Bsh) convertLineEndings(path string, fn func([]byte) []byte) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		b.Panic(err)
+		return false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		b.Panic(err)
+		return false
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		b.Verbosef("Skipping binary file: %s", path)
+		return false
+	}
+	converted := fn(data)
+	if bytes.Equal(data, converted) {
+		return false
+	}
+	if err := os.WriteFile(path, converted, info.Mode().Perm()); err != nil {
+		b.Panic(err)
+		return false
+	}
+	return true
+}
+
+// Key/value files
+
+// ReadKeyValue reads a file of "key=value" lines into a map, where sep is the separator
+// between each key and value (defaults to "=" if empty). Leading and trailing whitespace is
+// trimmed from keys and values. Blank lines, comment lines (starting with # or ;), and lines
+// without a separator are skipped.
+func (b *Bsh) ReadKeyValue(path string, sep string) map[string]string {
+	if len(sep) == 0 {
+		sep = "="
+	}
+	b.Verbosef("Read key/values from file: %s", path)
+	m := make(map[string]string)
+	for _, line := range strings.Split(b.Read(path), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		i := strings.Index(line, sep)
+		if i < 0 {
+			continue
+		}
+		m[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+len(sep):])
+	}
+	return m
+}
+
+// WriteKeyValue writes each entry in m as a "key=value" line, where sep is the separator
+// between each key and value (defaults to "=" if empty). Keys are sorted, so the output is
+// stable.
+func (b *Bsh) WriteKeyValue(path string, m map[string]string, sep string) {
+	if len(sep) == 0 {
+		sep = "="
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(k)
+		sb.WriteString(sep)
+		sb.WriteString(m[k])
+		sb.WriteString("\n")
+	}
+	if err := b.writeImpl(path, sb.String(), nil, false); err != nil {
+		b.Panic(err)
+	}
+}
@@ -0,0 +1,54 @@
+package bsh
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeyValue(t *testing.T) {
+	ensureLocalFolder(t)
+	b := Bsh{}
+	b.InDir("local", func() {
+		b.Write("kv_test.ini", "# comment\n; another comment\n\n name = bsh \nurl=http://x?a=b\n[section]\n")
+		m := b.ReadKeyValue("kv_test.ini", "")
+		if len(m) != 2 || m["name"] != "bsh" || m["url"] != "http://x?a=b" {
+			t.Fatalf("unexpected result from ReadKeyValue: %v", m)
+		}
+
+		b.WriteKeyValue("kv_test2.ini", m, ": ")
+		actual := b.Read("kv_test2.ini")
+		expected := "name: bsh\nurl: http://x?a=b\n"
+		if actual != expected {
+			t.Errorf(`expected: "%s", but got "%s"`, expected, actual)
+		}
+	})
+}
+
+func TestValue(t *testing.T) {
+	ensureLocalFolder(t)
+	b := Bsh{}
+	b.WriteValue("local/value_test.txt", "\n  v1.2.3 \r\n\n")
+	if actual := b.Read("local/value_test.txt"); actual != "v1.2.3\n" {
+		t.Errorf(`expected WriteValue to write "v1.2.3\n", but got "%s"`, actual)
+	}
+	if actual := b.ReadValue("local/value_test.txt"); actual != "v1.2.3" {
+		t.Errorf(`expected ReadValue to return "v1.2.3", but got "%s"`, actual)
+	}
+}
+
+func TestReadHead(t *testing.T) {
+	ensureLocalFolder(t)
+	b := Bsh{}
+	b.InDir("local", func() {
+		long := strings.Repeat("x", 100*1024)
+		b.Write("read_head.txt", "one\r\n"+long+"\nthree\nfour")
+
+		lines := b.ReadHead("read_head.txt", 3)
+		if len(lines) != 3 || lines[0] != "one" || lines[1] != long || lines[2] != "three" {
+			t.Errorf("expected the first 3 lines (including a 100KB line), but got %d line(s)", len(lines))
+		}
+		if lines = b.ReadHead("read_head.txt", 10); len(lines) != 4 || lines[3] != "four" {
+			t.Errorf(`expected 4 lines ending with "four", but got %d line(s)`, len(lines))
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package bsh
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// GitHub Actions workflow commands.
+// See https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions
+// When not running in GitHub Actions (ie GITHUB_ACTIONS is not "true"), these fall back to
+// plain output.
+
+// IsGHA returns true if running inside a GitHub Actions workflow.
+func (b *Bsh) IsGHA() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// GHAGroup runs fn with any output nested inside a collapsible group in the GitHub Actions log.
+func (b *Bsh) GHAGroup(title string, fn func()) {
+	if !b.IsGHA() {
+		b.Echo(title)
+		fn()
+		return
+	}
+	b.echo("::group::"+ghaEscapeData(title), ensureNewline)
+	defer b.echo("::endgroup::", ensureNewline)
+	fn()
+}
+
+// GHASetOutput sets an output parameter for the current step, by appending to the file
+// specified by GITHUB_OUTPUT.
+func (b *Bsh) GHASetOutput(name, value string) {
+	path := os.Getenv("GITHUB_OUTPUT")
+	if !b.IsGHA() || len(path) == 0 {
+		b.Verbosef("GHASetOutput: %s=%s", name, value)
+		return
+	}
+	var entry string
+	if strings.ContainsAny(value, "\r\n") {
+		delim := "ghadelimiter_" + hashString(value)[:16]
+		entry = fmt.Sprintf("%s<<%s\n%s\n%s\n", name, delim, value, delim)
+	} else {
+		entry = fmt.Sprintf("%s=%s\n", name, value)
+	}
+	if err := b.writeImpl(path, entry, nil, true); err != nil {
+		b.Panic(err)
+	}
+}
+
+// GHAError creates an error annotation in the GitHub Actions log for the given file and line.
+func (b *Bsh) GHAError(file string, line int, msg string) {
+	if !b.IsGHA() {
+		b.Warnf("error: %s:%d: %s", file, line, msg)
+		return
+	}
+	b.echo(fmt.Sprintf("::error file=%s,line=%d::%s", ghaEscapeProperty(file), line, ghaEscapeData(msg)), ensureNewline)
+}
+
+func ghaEscapeData(str string) string {
+	str = strings.ReplaceAll(str, "%", "%25")
+	str = strings.ReplaceAll(str, "\r", "%0D")
+	return strings.ReplaceAll(str, "\n", "%0A")
+}
+
+func ghaEscapeProperty(str string) string {
+	str = ghaEscapeData(str)
+	str = strings.ReplaceAll(str, ":", "%3A")
+	return strings.ReplaceAll(str, ",", "%2C")
+}
@@ -3,6 +3,8 @@ module github.com/danbrakeley/bsh
 go 1.16
 
 require (
+	github.com/creack/pty v1.1.18
 	github.com/danbrakeley/commandline v1.0.0
 	github.com/magefile/mage v1.15.0
+	golang.org/x/term v0.0.0-20210927222741-03fcf44c2211
 )
@@ -0,0 +1,94 @@
+package bsh
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+func (b *Bsh) GzipFile(source, target string) {
+	if err := b.GzipFileErr(source, target); err != nil {
+		b.Panic(err)
+	}
+}
+
+func (b *Bsh) GzipFileErr(source, target string) error {
+	b.Verbosef("GzipFile: %s to %s", source, target)
+	return gzipFile(source, target)
+}
+
+func (b *Bsh) GunzipFile(source, target string) {
+	if err := b.GunzipFileErr(source, target); err != nil {
+		b.Panic(err)
+	}
+}
+
+func (b *Bsh) GunzipFileErr(source, target string) error {
+	b.Verbosef("GunzipFile: %s to %s", source, target)
+	return gunzipFile(source, target)
+}
+
+func gzipFile(source, target string) error {
+	fsrc, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer fsrc.Close()
+
+	info, err := fsrc.Stat()
+	if err != nil {
+		return err
+	}
+
+	fgz, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer fgz.Close()
+
+	gw := gzip.NewWriter(fgz)
+	gw.Name = filepath.Base(source)
+	gw.ModTime = info.ModTime()
+
+	if _, err := io.Copy(gw, fsrc); err != nil {
+		gw.Close()
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	return fgz.Close()
+}
+
+func gunzipFile(source, target string) error {
+	fgz, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer fgz.Close()
+
+	gr, err := gzip.NewReader(fgz)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+
+	fdst, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer fdst.Close()
+
+	if _, err := io.Copy(fdst, gr); err != nil {
+		return err
+	}
+	if err := fdst.Close(); err != nil {
+		return err
+	}
+
+	if !gr.ModTime.IsZero() {
+		return os.Chtimes(target, gr.ModTime, gr.ModTime)
+	}
+	return nil
+}
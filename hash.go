@@ -0,0 +1,77 @@
+package bsh
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HashDir returns a hex-encoded SHA256 digest that represents the structure and contents of
+// everything inside root. The walk is done in lexical order, so the hash is stable as long as
+// the relative paths and file contents don't change. File times and modes are ignored.
+func (b *Bsh) HashDir(root string) string {
+	b.Verbosef("HashDir: %s", root)
+	h := sha256.New()
+	if err := hashDirInto(h, root); err != nil {
+		b.Panic(err)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func hashDirInto(h io.Writer, root string) error {
+	return fs.WalkDir(os.DirFS(root), ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == "." {
+			return nil
+		}
+		if d.IsDir() {
+			fmt.Fprintf(h, "d %s\x00", path)
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			fmt.Fprintf(h, "o %s\x00", path)
+			return nil
+		}
+		fmt.Fprintf(h, "f %s\x00%d\x00", path, info.Size())
+		return copyFileTo(h, filepath.Join(root, filepath.FromSlash(path)))
+	})
+}
+
+// VerifyChecksum returns true if the file at path matches the expected hex-encoded digest.
+// The hash algorithm is inferred from the length of expected: 32 characters for MD5, 64 for
+// SHA256, or 128 for SHA512. The comparison is case-insensitive.
+func (b *Bsh) VerifyChecksum(path, expected string) bool {
+	expected = strings.ToLower(strings.TrimSpace(expected))
+	var h hash.Hash
+	switch len(expected) {
+	case 32:
+		h = md5.New()
+	case 64:
+		h = sha256.New()
+	case 128:
+		h = sha512.New()
+	default:
+		b.Panic(fmt.Errorf("unable to infer hash algorithm from checksum %q", expected))
+		return false
+	}
+	b.Verbosef("VerifyChecksum: %s", path)
+	if err := copyFileTo(h, path); err != nil {
+		b.Panic(err)
+		return false
+	}
+	return hex.EncodeToString(h.Sum(nil)) == expected
+}
@@ -0,0 +1,76 @@
+package bsh
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// UpdateJSON reads the JSON file at path into v (leaving v as-is if the file doesn't exist),
+// calls modify (which is expected to change v), then writes v back to path as indented JSON.
+// The write is atomic: the new contents are written to a temp file that then replaces path.
+// If DryRun is set, the write is echoed instead.
+func (b *Bsh) UpdateJSON(path string, v interface{}, modify func()) {
+	b.Verbosef("UpdateJSON: %s", path)
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, v); err != nil {
+			b.Panic(err)
+			return
+		}
+	case !os.IsNotExist(err):
+		b.Panic(err)
+		return
+	}
+
+	modify()
+
+	data, err = json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		b.Panic(err)
+		return
+	}
+	data = append(data, '\n')
+	if b.DryRun {
+		err = b.dryRunWrite(path, data, false)
+	} else {
+		err = writeFileAtomic(path, data)
+	}
+	if err != nil {
+		b.Panic(err)
+	}
+}
+
+// writeFileAtomic writes data to a temp file in the same folder as path, then renames it to path,
+// so that readers of path never see a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	// CreateTemp always uses mode 0600, so switch to the mode of the file being replaced
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.Chmod(tmp, mode); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package bsh
+
+import (
+	"io"
+	"testing"
+)
+
+func TestUpdateJSON(t *testing.T) {
+	ensureLocalFolder(t)
+	b := Bsh{DisableColor: true, Stdout: io.Discard}
+	b.InDir("local", func() {
+		b.RemoveAll("state.json")
+
+		type state struct {
+			Builds int      `json:"builds"`
+			Tags   []string `json:"tags"`
+		}
+		for i := 0; i < 2; i++ {
+			var s state
+			b.UpdateJSON("state.json", &s, func() {
+				s.Builds++
+				s.Tags = append(s.Tags, "v1")
+			})
+		}
+
+		actual := b.Read("state.json")
+		expected := "{\n  \"builds\": 2,\n  \"tags\": [\n    \"v1\",\n    \"v1\"\n  ]\n}\n"
+		if actual != expected {
+			t.Errorf(`expected: "%s", but got "%s"`, expected, actual)
+		}
+
+		b.DryRun = true
+		var s state
+		b.UpdateJSON("state.json", &s, func() { s.Builds++ })
+		b.DryRun = false
+		if actual := b.Read("state.json"); actual != expected {
+			t.Errorf(`expected dry run to leave file unchanged, but it contains "%s"`, actual)
+		}
+	})
+}
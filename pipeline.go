@@ -0,0 +1,170 @@
+package bsh
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Pipeline is a sequence of commands, where the stdout of each command is connected to the stdin
+// of the next, as returned by Pipe.
+type Pipeline struct {
+	b    *Bsh
+	cmds []*Command
+}
+
+// Pipe connects the stdout of each command to the stdin of the next, like a shell pipeline, but
+// without needing a shell. The stdin of the first command and the stdout of the last command are
+// left as they were set on those commands. Stderr of every command goes to its usual destination
+// (by default, this instance's Stderr).
+func (b *Bsh) Pipe(cmds ...*Command) *Pipeline {
+	return &Pipeline{b: b, cmds: cmds}
+}
+
+// String returns each command string, separated by " | ".
+func (p *Pipeline) String() string {
+	strs := make([]string, len(p.cmds))
+	for i, c := range p.cmds {
+		strs[i] = c.String()
+	}
+	return strings.Join(strs, " | ")
+}
+
+func (p *Pipeline) Run() {
+	if err := p.run(); err != nil {
+		p.b.Warnf("unexpected error in %s", p.String())
+		p.b.Panic(err)
+	}
+}
+
+// RunStr captures the stdout of the last command, and returns it.
+func (p *Pipeline) RunStr() string {
+	var sb strings.Builder
+	if len(p.cmds) > 0 {
+		p.cmds[len(p.cmds)-1].out = &sb
+	}
+	p.Run()
+	return sb.String()
+}
+
+// RunErr returns an error if any command fails, which says which command failed, and with what
+// exit status. If more than one command fails, only the first failure is reported.
+func (p *Pipeline) RunErr() error {
+	return p.run()
+}
+
+// run starts every command, then waits for all of them to exit. The commands are connected with
+// OS pipes (rather than io.Pipe), so each process reads directly from the one before it, and a
+// process that exits early (eg "head") causes the processes before it to see a closed pipe.
+// If a command's stdout is wrapped (eg by OutTransform or TailBuffer), os/exec copies its output
+// into the pipe from a goroutine, so the write end of each pipe is only closed once the command
+// writing to it has been waited on.
+func (p *Pipeline) run() error {
+	if len(p.cmds) == 0 {
+		return fmt.Errorf("empty pipeline")
+	}
+	p.b.Verbosef("Pipe: %s", p.String())
+
+	started := make([]*exec.Cmd, 0, len(p.cmds))
+	writers := make([]*os.File, 0, len(p.cmds))
+	var prevReader *os.File
+	closeWriter := func(i int) {
+		if i < len(writers) && writers[i] != nil {
+			writers[i].Close()
+		}
+	}
+	cleanup := func() {
+		if prevReader != nil {
+			prevReader.Close()
+		}
+		for i, cmd := range started {
+			cmd.Process.Kill()
+			p.cmds[i].finish(cmd.Wait())
+			closeWriter(i)
+		}
+	}
+
+	for i, c := range p.cmds {
+		name, args, err := c.parse()
+		if err != nil {
+			cleanup()
+			return p.stageErr(i, err)
+		}
+
+		var writer *os.File
+		if prevReader != nil {
+			c.in = prevReader
+		}
+		var nextReader *os.File
+		if i < len(p.cmds)-1 {
+			if nextReader, writer, err = os.Pipe(); err != nil {
+				cleanup()
+				return err
+			}
+			c.pipeOutTo(writer)
+		}
+
+		cmd := c.newCmd(name, args...)
+		err = cmd.Start()
+		// the child process has its own copy of this now
+		if prevReader != nil {
+			prevReader.Close()
+		}
+		prevReader = nextReader
+		if err != nil {
+			if writer != nil {
+				writer.Close()
+			}
+			cleanup()
+			return p.stageErr(i, c.finish(err))
+		}
+		c.watchTimeout(cmd)
+		started = append(started, cmd)
+		writers = append(writers, writer)
+	}
+
+	var firstErr error
+	for i, cmd := range started {
+		err := p.cmds[i].finish(cmd.Wait())
+		closeWriter(i)
+		// like a shell, ignore a command being stopped because a later command stopped reading
+		if i < len(started)-1 && killedByBrokenPipe(cmd.ProcessState) {
+			err = nil
+		}
+		if err != nil && firstErr == nil {
+			firstErr = p.stageErr(i, err)
+		}
+	}
+	return firstErr
+}
+
+// pipeOutTo sends stdout to w instead of where it was going, but keeps any OutTransform wrapped
+// around it, so the transform still applies to what is passed to the next command.
+func (c *Command) pipeOutTo(w io.Writer) {
+	lw, ok := c.out.(*lineWriter)
+	if !ok {
+		c.out = w
+		return
+	}
+	for {
+		inner, ok := lw.w.(*lineWriter)
+		if !ok {
+			break
+		}
+		lw = inner
+	}
+	lw.w = w
+}
+
+// stageErr wraps err with which command in the pipeline it came from
+func (p *Pipeline) stageErr(i int, err error) error {
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		return fmt.Errorf("pipeline command %d of %d (%s) failed with exit status %d: %w",
+			i+1, len(p.cmds), p.cmds[i].String(), ee.ExitCode(), err)
+	}
+	return fmt.Errorf("pipeline command %d of %d (%s) failed: %w", i+1, len(p.cmds), p.cmds[i].String(), err)
+}
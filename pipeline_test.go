@@ -0,0 +1,43 @@
+package bsh
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPipe(t *testing.T) {
+	b := Bsh{}
+	actual := b.Pipe(
+		b.Cmd(`printf "apple\nbanana\navocado\n"`),
+		b.Cmd("grep a"),
+		b.Cmd("grep -v banana"),
+	).RunStr()
+	if actual != "apple\navocado\n" {
+		t.Errorf(`expected "apple\navocado\n", but got "%s"`, actual)
+	}
+
+	// a process that exits early shouldn't hang the processes before it
+	actual = b.Pipe(b.Cmd("yes"), b.Cmd("head -n 2")).RunStr()
+	if actual != "y\ny\n" {
+		t.Errorf(`expected "y\ny\n", but got "%s"`, actual)
+	}
+
+	err := b.Pipe(b.Cmd("echo hi"), b.Cmd(`bash -c "cat; exit 4"`), b.Cmd("cat")).RunErr()
+	if err == nil || !strings.Contains(err.Error(), "command 2 of 3") || !strings.Contains(err.Error(), "exit status 4") {
+		t.Errorf("expected error to identify the failed command, but got %v", err)
+	}
+}
+
+func TestPipeWrappedMiddle(t *testing.T) {
+	b := Bsh{}
+	mid := b.Cmd("tr a-z A-Z").TailBuffer(64).OutTransform(func(line string) string {
+		return "x" + line
+	})
+	actual := b.Pipe(b.CmdArgs("printf", `a\nb\nc\n`), mid, b.Cmd("cat")).RunStr()
+	if actual != "xA\nxB\nxC\n" {
+		t.Errorf(`expected "xA\nxB\nxC\n", but got "%s"`, actual)
+	}
+	if tail := mid.LastOutput(); tail != "A\nB\nC\n" {
+		t.Errorf(`expected the middle command's tail to be "A\nB\nC\n", but got "%s"`, tail)
+	}
+}
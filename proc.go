@@ -0,0 +1,63 @@
+package bsh
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IsProcessAlive returns true if a process with the given pid is currently running.
+func (b *Bsh) IsProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	return isProcessAlive(pid)
+}
+
+// ReadPIDFile reads a process id from a file that contains only that pid (and optional whitespace).
+func (b *Bsh) ReadPIDFile(path string) int {
+	b.Verbosef("ReadPIDFile: %s", path)
+	str := strings.TrimSpace(b.Read(path))
+	pid, err := strconv.Atoi(str)
+	if err != nil {
+		b.Panic(fmt.Errorf("%s does not contain a valid pid: %w", path, err))
+	}
+	return pid
+}
+
+// StopProcess asks the process with the given pid to exit (via SIGTERM on Unix, or taskkill
+// without /F on Windows), then waits up to graceTimeout for it to do so. If it is still running
+// after that, it is forcibly killed (via SIGKILL on Unix, or TerminateProcess on Windows).
+// Returns true if a forced kill was needed.
+func (b *Bsh) StopProcess(pid int, graceTimeout time.Duration) (bool, error) {
+	b.Verbosef("StopProcess: %d (grace period %v)", pid, graceTimeout)
+	if !b.IsProcessAlive(pid) {
+		return false, nil
+	}
+	if err := terminateProcess(pid); err != nil {
+		b.Verbosef("Unable to ask process %d to exit: %v", pid, err)
+	}
+
+	deadline := time.Now().Add(graceTimeout)
+	for time.Now().Before(deadline) {
+		if !isProcessAlive(pid) {
+			return false, nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if !isProcessAlive(pid) {
+		return false, nil
+	}
+
+	b.Verbosef("Process %d did not exit within %v, killing it", pid, graceTimeout)
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return true, err
+	}
+	if err := p.Kill(); err != nil && isProcessAlive(pid) {
+		return true, err
+	}
+	return true, nil
+}
@@ -0,0 +1,33 @@
+package bsh
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestStopProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses unix signals")
+	}
+	b := Bsh{}
+
+	rc := b.Cmd("sleep 10").Start()
+	forced, err := b.StopProcess(rc.Pid(), 5*time.Second)
+	if err != nil || forced {
+		t.Errorf("expected the process to exit when asked, but got forced: %v (%v)", forced, err)
+	}
+	<-rc.Done()
+
+	// a process that ignores SIGTERM has to be killed once the grace period is over
+	rc = b.Cmd(`bash -c "trap '' TERM; exec sleep 10"`).Start()
+	time.Sleep(100 * time.Millisecond) // give bash time to set up the trap
+	forced, err = b.StopProcess(rc.Pid(), 200*time.Millisecond)
+	if err != nil || !forced {
+		t.Errorf("expected the process to be killed, but got forced: %v (%v)", forced, err)
+	}
+	<-rc.Done()
+	if b.IsProcessAlive(rc.Pid()) {
+		t.Error("expected the process to no longer be alive")
+	}
+}
@@ -0,0 +1,32 @@
+//go:build !windows
+// +build !windows
+
+package bsh
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+func isProcessAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// signal 0 performs error checking without actually sending a signal
+	err = p.Signal(syscall.Signal(0))
+	if err == nil {
+		return true
+	}
+	// EPERM means the process exists, but we aren't allowed to signal it
+	return errors.Is(err, syscall.EPERM)
+}
+
+func terminateProcess(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Signal(syscall.SIGTERM)
+}
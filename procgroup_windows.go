@@ -0,0 +1,16 @@
+package bsh
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Windows has no process groups that can be killed as a unit, so only the process itself is
+// killed.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func killProcessGroup(pid int) {
+	if p, err := os.FindProcess(pid); err == nil {
+		p.Kill()
+	}
+}
@@ -0,0 +1,25 @@
+package bsh
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestRunPTY(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("RunPTY is not supported on windows")
+	}
+	b := Bsh{}
+	actual, err := b.Cmd(`bash -c "[ -t 0 ] && [ -t 1 ] && echo out; echo err >&2"`).RunPTY()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the terminal translates each newline to CRLF
+	if actual != "out\r\nerr\r\n" {
+		t.Errorf(`expected stdin and stdout to be a terminal, and stderr to be combined, but got %q`, actual)
+	}
+
+	if _, err := b.Cmd(`bash -c "exit 3"`).RunPTY(); err == nil {
+		t.Error("expected an error from a failing command")
+	}
+}
@@ -0,0 +1,51 @@
+//go:build !windows
+// +build !windows
+
+package bsh
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"syscall"
+
+	"github.com/creack/pty"
+)
+
+// RunPTY runs the command attached to a newly allocated pseudo-terminal, and returns everything
+// the process wrote to it (including any ANSI escape codes). This is useful for capturing the
+// output of tools that behave differently (eg only use color) when attached to a terminal.
+// Note that stdout and stderr are both written to the terminal, so they are combined.
+// Not supported on Windows.
+func (c *Command) RunPTY() (string, error) {
+	name, args, err := c.parse()
+	if err != nil {
+		return "", err
+	}
+	c.b.Verbosef("Exec (pty): %s", c.String())
+	cmd := c.newCmd(name, args...)
+	// pty.Start attaches the pty to any of these that are nil
+	cmd.Stdin = nil
+	cmd.Stdout = nil
+	cmd.Stderr = nil
+	// pty.Start makes the process a session leader (and so a process group leader), and
+	// setpgid fails for session leaders
+	cmd.SysProcAttr = nil
+
+	f, err := pty.Start(cmd)
+	if err != nil {
+		return "", c.finish(err)
+	}
+	defer f.Close()
+	c.watchTimeout(cmd)
+
+	var buf bytes.Buffer
+	_, err = io.Copy(&buf, f)
+	// on Linux, reading from the pty after the process exits returns EIO instead of EOF
+	if err != nil && !errors.Is(err, syscall.EIO) && !errors.Is(err, os.ErrClosed) {
+		c.finish(cmd.Wait())
+		return buf.String(), err
+	}
+	return buf.String(), c.finish(cmd.Wait())
+}
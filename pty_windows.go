@@ -0,0 +1,10 @@
+package bsh
+
+import (
+	"errors"
+)
+
+// RunPTY is not supported on Windows, and always returns an error.
+func (c *Command) RunPTY() (string, error) {
+	return "", errors.New("RunPTY is not supported on Windows")
+}
@@ -0,0 +1,74 @@
+package bsh
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// CommandResult holds everything about a single run of a Command.
+type CommandResult struct {
+	Command    string // the raw command string
+	Stdout     string // everything the process wrote to stdout
+	Stderr     string // everything the process wrote to stderr
+	ExitStatus int    // the exit status, or -1 if the process didn't run or exit normally
+	Err        error  // any error returned from running the process
+}
+
+// RunResult runs the command, capturing stdout and stderr separately, and returns the outcome
+// as a CommandResult. It never panics; check the result's Err instead.
+func (c *Command) RunResult() CommandResult {
+	var stdout, stderr strings.Builder
+	c.out = &stdout
+	c.err = &stderr
+	err := c.run()
+	n, e := extractExitStatus(err)
+	if e != nil {
+		n = -1
+	}
+	return CommandResult{
+		Command:    c.String(),
+		Stdout:     stdout.String(),
+		Stderr:     stderr.String(),
+		ExitStatus: n,
+		Err:        err,
+	}
+}
+
+// InResult uses the stdout captured in r as this command's stdin.
+func (c *Command) InResult(r CommandResult) *Command {
+	c.in = strings.NewReader(r.Stdout)
+	return c
+}
+
+// RunAllResults runs each command in order (via RunResult), and returns all of their results,
+// even if some of them fail. It never panics; check each result's Err instead.
+func (b *Bsh) RunAllResults(commands ...*Command) []CommandResult {
+	results := make([]CommandResult, len(commands))
+	for i, c := range commands {
+		results[i] = c.RunResult()
+	}
+	return results
+}
+
+// RunAllResultsParallel is RunAllResults, but runs up to maxParallel commands at once (if
+// maxParallel < 1, it defaults to the number of CPUs). Results are in the same order as commands.
+func (b *Bsh) RunAllResultsParallel(maxParallel int, commands ...*Command) []CommandResult {
+	if maxParallel < 1 {
+		maxParallel = runtime.NumCPU()
+	}
+	results := make([]CommandResult, len(commands))
+	sem := make(chan struct{}, maxParallel)
+	var wg sync.WaitGroup
+	for i, c := range commands {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, c *Command) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = c.RunResult()
+		}(i, c)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,180 @@
+package bsh
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"time"
+)
+
+// Retry calls fn until it returns nil, up to attempts times. After each failure, it sleeps
+// before trying again, starting with delay and doubling the delay after each attempt.
+// Returns nil on success, or the error from the last attempt if every attempt failed.
+// fn is always called at least once.
+func (b *Bsh) Retry(attempts int, delay time.Duration, fn func() error) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+	var err error
+	for i := 1; i <= attempts; i++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if i < attempts {
+			b.Verbosef("Retry: attempt %d of %d failed (%v), retrying in %v", i, attempts, err, delay)
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	return err
+}
+
+// QuietRetries causes RunRetry to buffer the output of each attempt, discarding it if the attempt
+// fails, so that only the output of the attempt that matters (the first success, or the final
+// failure) reaches the command's writers. Note that buffered stdout is replayed before buffered
+// stderr, so their relative ordering is lost unless Out and Err are the same writer.
+func (c *Command) QuietRetries() *Command {
+	c.quietRetry = true
+	return c
+}
+
+// RunRetry runs the command up to attempts times, sleeping for delay after each failure.
+// Returns nil on the first success, or the error from the last attempt if every attempt failed.
+// The command is always run at least once.
+// If Out or Err is a buffer with a Reset method (eg bytes.Buffer or strings.Builder), it is reset
+// before each retry, so it only holds the output of the final attempt. Other writers receive the
+// output of every attempt (unless QuietRetries is set).
+// If stdin can seek (eg when set via StdinStr, StdinBytes, or In with a file), it is rewound before
+// each retry, so every attempt reads the same input. Otherwise, whatever the earlier attempts read
+// from stdin is not seen again by later attempts.
+func (c *Command) RunRetry(attempts int, delay time.Duration) error {
+	return c.runRetry(attempts, func() time.Duration { return delay })
+}
+
+// RetryPolicy controls the delays between attempts made by RunRetryPolicy.
+type RetryPolicy struct {
+	BaseDelay  time.Duration // delay after the first failure
+	MaxDelay   time.Duration // the delay never grows beyond this (no limit if <= 0)
+	Multiplier float64       // each delay is the previous delay times this (treated as 1 if < 1)
+	Jitter     float64       // up to this fraction of each delay is randomly added to it (eg 0.2 for up to 20%)
+}
+
+// DefaultRetryPolicy starts with a half second delay, doubling it after each failure up to a
+// max of 30 seconds, with up to 20% jitter.
+var DefaultRetryPolicy = RetryPolicy{
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   30 * time.Second,
+	Multiplier: 2,
+	Jitter:     0.2,
+}
+
+// RunRetryPolicy is RunRetry, but the delay between attempts grows according to p. Random
+// jitter is added to each delay so that many scripts retrying at once don't do so in lockstep.
+func (c *Command) RunRetryPolicy(attempts int, p RetryPolicy) error {
+	delay := p.BaseDelay
+	return c.runRetry(attempts, func() time.Duration {
+		d := delay
+		delay = p.next(delay)
+		if p.Jitter > 0 {
+			d += time.Duration(rand.Float64() * p.Jitter * float64(d))
+		}
+		return d
+	})
+}
+
+// next returns the delay to use after delay
+func (p RetryPolicy) next(delay time.Duration) time.Duration {
+	if p.Multiplier > 1 {
+		delay = time.Duration(float64(delay) * p.Multiplier)
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// runRetry runs the command up to attempts times, calling nextDelay after each failure to get
+// how long to sleep before trying again.
+func (c *Command) runRetry(attempts int, nextDelay func() time.Duration) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+	rewind := stdinRewinder(c.in)
+	var err error
+	for i := 1; i <= attempts; i++ {
+		if i > 1 {
+			resetWriter(c.out)
+			resetWriter(c.err)
+			if rewind != nil {
+				if err := rewind(); err != nil {
+					return err
+				}
+			}
+		}
+		if err = c.runAttempt(i == attempts); err == nil {
+			return nil
+		}
+		if i < attempts {
+			delay := nextDelay()
+			c.b.Verbosef("RunRetry: attempt %d of %d failed (%v), retrying in %v", i, attempts, err, delay)
+			time.Sleep(delay)
+		}
+	}
+	return err
+}
+
+// runAttempt runs the command once. If QuietRetries is set and this isn't the last attempt, the
+// output is buffered, and only written to the real writers if the attempt succeeds.
+func (c *Command) runAttempt(last bool) error {
+	if !c.quietRetry || last {
+		return c.run()
+	}
+
+	out, errw := c.out, c.err
+	bufOut := &bytes.Buffer{}
+	bufErr := bufOut
+	if !sameWriter(out, errw) {
+		bufErr = &bytes.Buffer{}
+	}
+	c.out, c.err = bufOut, bufErr
+	err := c.run()
+	c.out, c.err = out, errw
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, bufOut); err != nil {
+		return err
+	}
+	if bufErr != bufOut {
+		if _, err := io.Copy(errw, bufErr); err != nil {
+			return err
+		}
+	}
+	c.flushLineWriters()
+	return nil
+}
+
+// stdinRewinder returns a func that seeks r back to its current position, or nil if r can't seek
+// (eg a pipe or a terminal).
+func stdinRewinder(r io.Reader) func() error {
+	s, ok := r.(io.Seeker)
+	if !ok {
+		return nil
+	}
+	pos, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil
+	}
+	return func() error {
+		_, err := s.Seek(pos, io.SeekStart)
+		return err
+	}
+}
+
+// resetWriter calls Reset on w, if it has such a method
+func resetWriter(w io.Writer) {
+	if r, ok := w.(interface{ Reset() }); ok {
+		r.Reset()
+	}
+}
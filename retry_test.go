@@ -0,0 +1,69 @@
+package bsh
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunRetryQuiet(t *testing.T) {
+	ensureLocalFolder(t)
+	b := Bsh{}
+	b.RemoveAll("local/retry_count")
+	var out strings.Builder
+	script := `n=$(cat local/retry_count 2>/dev/null || echo 0); n=$((n+1)); echo $n > local/retry_count; echo attempt $n; [ $n -ge 3 ]`
+	err := b.Cmd("bash -c '"+script+"'").Out(&out).QuietRetries().RunRetry(5, 0)
+	if err != nil {
+		t.Fatalf("expected success, but got %v", err)
+	}
+	if out.String() != "attempt 3\n" {
+		t.Errorf(`expected only output from the successful attempt, but got "%s"`, out.String())
+	}
+}
+
+func TestRunRetryResetsBuffers(t *testing.T) {
+	ensureLocalFolder(t)
+	b := Bsh{}
+	b.RemoveAll("local/retry_count2")
+	var out bytes.Buffer
+	script := `n=$(cat local/retry_count2 2>/dev/null || echo 0); n=$((n+1)); echo $n > local/retry_count2; echo attempt $n; [ $n -ge 2 ]`
+	if err := b.Cmd("bash -c '"+script+"'").Out(&out).RunRetry(3, 0); err != nil {
+		t.Fatalf("expected success, but got %v", err)
+	}
+	if out.String() != "attempt 2\n" {
+		t.Errorf(`expected buffer to only hold the last attempt, but got "%s"`, out.String())
+	}
+}
+
+func TestRunRetryStdin(t *testing.T) {
+	ensureLocalFolder(t)
+	b := Bsh{}
+	b.InDir("local", func() {
+		b.RemoveAll("retry_stdin.txt")
+		script := `read line; echo "$line" >> retry_stdin.txt; test $(wc -l < retry_stdin.txt) -ge 2`
+		err := b.CmdArgs("bash", "-c", script).StdinStr("hello\n").RunRetry(2, time.Millisecond)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if actual := b.Read("retry_stdin.txt"); actual != "hello\nhello\n" {
+			t.Errorf(`expected both attempts to read "hello", but got "%s"`, actual)
+		}
+	})
+}
+
+func TestRetryPolicyNext(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second, Multiplier: 2}
+	delay := p.BaseDelay
+	var actual []time.Duration
+	for i := 0; i < 5; i++ {
+		actual = append(actual, delay)
+		delay = p.next(delay)
+	}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Fatalf("expected delays %v, but got %v", expected, actual)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package bsh
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// RLimitCPU limits the CPU time the process may use to the given number of seconds (Unix only).
+// Both the soft and hard limits are set, so when the limit is reached the process is sent
+// SIGXCPU and/or SIGKILL (Linux sends SIGKILL when the hard limit is reached).
+// On Windows, this logs a warning and has no effect.
+func (c *Command) RLimitCPU(seconds int) *Command {
+	if runtime.GOOS == "windows" {
+		c.b.Warn("RLimitCPU is not supported on Windows, and will be ignored")
+		return c
+	}
+	c.rlimitCPU = seconds
+	return c
+}
+
+// RLimitAS limits the virtual address space the process may use to the given number of bytes,
+// rounded up to the nearest KiB (Unix only). No signal is sent when the limit is reached;
+// instead, attempts to allocate more memory fail, which usually causes the process to abort
+// (eg with SIGABRT or SIGSEGV, or a Go runtime "out of memory" fatal error).
+// On Windows, this logs a warning and has no effect.
+func (c *Command) RLimitAS(bytes uint64) *Command {
+	if runtime.GOOS == "windows" {
+		c.b.Warn("RLimitAS is not supported on Windows, and will be ignored")
+		return c
+	}
+	c.rlimitAS = bytes
+	return c
+}
+
+// withRLimits returns the name and args to execute so that any rlimits are applied.
+// Go's os/exec has no way to set rlimits in the child before exec, so instead the process
+// is launched via sh, which sets the limits via ulimit, then execs the real process.
+func (c *Command) withRLimits(name string, args []string) (string, []string) {
+	if c.rlimitCPU <= 0 && c.rlimitAS == 0 {
+		return name, args
+	}
+	limits := make([]string, 0, 2)
+	if c.rlimitCPU > 0 {
+		limits = append(limits, fmt.Sprintf("ulimit -t %d", c.rlimitCPU))
+	}
+	if c.rlimitAS > 0 {
+		limits = append(limits, fmt.Sprintf("ulimit -v %d", (c.rlimitAS+1023)/1024))
+	}
+	c.b.Verbosef("+RLimits: %s", strings.Join(limits, ", "))
+	script := strings.Join(limits, " && ") + ` && exec "$@"`
+	return "/bin/sh", append([]string{"-c", script, "sh", name}, args...)
+}
@@ -0,0 +1,24 @@
+package bsh
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestRLimitPassesArgs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("rlimits are not supported on windows")
+	}
+	b := Bsh{}
+	// args that sh would otherwise split, expand, or unquote
+	args := []string{`%s|`, "has space", "$HOME", `'quoted'`, "*", ""}
+	actual := b.CmdArgs("printf", args...).RLimitCPU(60).RLimitAS(4 << 30).RunStr()
+	if actual != `has space|$HOME|'quoted'|*||` {
+		t.Errorf(`expected args to be passed through unchanged, but got "%s"`, actual)
+	}
+
+	actual = b.Cmd(`bash -c "ulimit -t"`).RLimitCPU(60).RunStr()
+	if actual != "60\n" {
+		t.Errorf(`expected the CPU limit to be 60, but got "%s"`, actual)
+	}
+}
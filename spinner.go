@@ -0,0 +1,65 @@
+package bsh
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+const spinnerInterval = 100 * time.Millisecond
+
+// RunWithSpinner runs command while showing a spinner next to label (or, if Stdout isn't a
+// terminal, just echoing label), capturing the command's stdout and stderr. When the command
+// finishes, a success line is echoed that includes how long it took, or if the command failed,
+// its captured output is echoed, followed by a failure line.
+// The error from running the command is returned (not panicked).
+func (b *Bsh) RunWithSpinner(label, command string) error {
+	stop := b.startSpinner(label)
+	start := time.Now()
+	var sb strings.Builder
+	err := b.Cmd(command).OutErr(&sb).RunErr()
+	elapsed := time.Since(start)
+	stop()
+
+	if err != nil && sb.Len() > 0 {
+		b.echo(sb.String(), ensureNewline, colorEcho)
+	}
+	b.echoResult(label, elapsed, err)
+	return err
+}
+
+// startSpinner animates a spinner next to label until the returned func is called, which clears
+// the spinner's line. If Stdout isn't a terminal, label is echoed once instead.
+func (b *Bsh) startSpinner(label string) func() {
+	if !b.isTerminal() {
+		b.echo(label, ensureNewline, colorEcho)
+		return func() {}
+	}
+
+	w := b.ensureStdout()
+	label = b.applyEchoFilters(label)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(spinnerInterval)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			fmt.Fprintf(w, "\r%s %s", spinnerFrames[i%len(spinnerFrames)], label)
+			select {
+			case <-done:
+				fmt.Fprint(w, "\r\033[K")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
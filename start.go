@@ -0,0 +1,174 @@
+package bsh
+
+import (
+	"io"
+	"os/exec"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// RunningCommand is a handle to a process that was started via Command.Start.
+type RunningCommand struct {
+	c    *Command
+	cmd  *exec.Cmd
+	done chan struct{}
+	err  error
+
+	mu      sync.Mutex
+	lines   []string      // every line of output so far
+	newLine chan struct{} // closed (and replaced) whenever a line is added
+}
+
+// Start starts the command in the background, and returns a handle that can be used to wait for
+// it to finish. Stdout and stderr are written to their usual destinations, and are also retained
+// line by line (for the life of the process), so they can be searched via WaitForOutput.
+func (c *Command) Start() *RunningCommand {
+	rc := &RunningCommand{
+		c:       c,
+		done:    make(chan struct{}),
+		lines:   make([]string, 0, 64),
+		newLine: make(chan struct{}),
+	}
+
+	name, args, err := c.parse()
+	if err != nil {
+		c.b.Panic(err)
+		return nil
+	}
+	c.b.Verbosef("Start: %s", c.String())
+
+	rc.cmd = c.newCmd(name, args...)
+	outLines := newLineWriter(io.Discard, rc.addLine)
+	c.lineWriters = append(c.lineWriters, outLines)
+	if sameWriter(rc.cmd.Stdout, rc.cmd.Stderr) {
+		// keep a single writer, so os/exec still copies both streams from one goroutine
+		rc.cmd.Stdout = teeWriter(rc.cmd.Stdout, outLines)
+		rc.cmd.Stderr = rc.cmd.Stdout
+	} else {
+		errLines := newLineWriter(io.Discard, rc.addLine)
+		c.lineWriters = append(c.lineWriters, errLines)
+		rc.cmd.Stdout = teeWriter(rc.cmd.Stdout, outLines)
+		rc.cmd.Stderr = teeWriter(rc.cmd.Stderr, errLines)
+	}
+	if err := rc.cmd.Start(); err != nil {
+		c.b.Warnf("unable to start %s", c.String())
+		c.b.Panic(c.finish(err))
+		return nil
+	}
+	c.watchTimeout(rc.cmd)
+
+	go func() {
+		rc.err = c.finish(rc.cmd.Wait())
+		close(rc.done)
+	}()
+	return rc
+}
+
+// Pid returns the process id of the running command.
+func (rc *RunningCommand) Pid() int {
+	return rc.cmd.Process.Pid
+}
+
+// Done returns a channel that is closed when the process exits.
+func (rc *RunningCommand) Done() <-chan struct{} {
+	return rc.done
+}
+
+// Wait blocks until the process exits, then returns any error from running it.
+func (rc *RunningCommand) Wait() error {
+	<-rc.done
+	return rc.err
+}
+
+// WaitForOutput blocks until the process writes a line (to stdout or stderr) that matches re,
+// and returns true. Lines written before this call are also checked. Returns false if the
+// process exits or the timeout elapses before a matching line is seen.
+func (rc *RunningCommand) WaitForOutput(re *regexp.Regexp, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	next := 0
+	for {
+		rc.mu.Lock()
+		lines := rc.lines[next:]
+		next = len(rc.lines)
+		newLine := rc.newLine
+		rc.mu.Unlock()
+
+		for _, line := range lines {
+			if re.MatchString(line) {
+				return true
+			}
+		}
+
+		select {
+		case <-newLine:
+		case <-rc.done:
+			// check any lines that were added before the process exited
+			rc.mu.Lock()
+			lines = rc.lines[next:]
+			rc.mu.Unlock()
+			for _, line := range lines {
+				if re.MatchString(line) {
+					return true
+				}
+			}
+			return false
+		case <-timer.C:
+			return false
+		}
+	}
+}
+
+func (rc *RunningCommand) addLine(line string) string {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.lines = append(rc.lines, line)
+	close(rc.newLine)
+	rc.newLine = make(chan struct{})
+	return ""
+}
+
+// OutputStream is a handle to a process that was started via Command.RunStreaming.
+type OutputStream struct {
+	rc *RunningCommand
+}
+
+// RunStreaming starts the command in the background, capturing its stdout and stderr (instead of
+// writing them to their usual destinations). The returned OutputStream can be used to read the
+// lines captured so far while the process is still running.
+func (c *Command) RunStreaming() *OutputStream {
+	c.out = io.Discard
+	c.err = io.Discard
+	rc := c.Start()
+	if rc == nil {
+		return nil
+	}
+	return &OutputStream{rc: rc}
+}
+
+// Lines returns a copy of every line of output captured so far.
+func (s *OutputStream) Lines() []string {
+	s.rc.mu.Lock()
+	defer s.rc.mu.Unlock()
+	lines := make([]string, len(s.rc.lines))
+	copy(lines, s.rc.lines)
+	return lines
+}
+
+// Done returns a channel that is closed when the process exits.
+func (s *OutputStream) Done() <-chan struct{} {
+	return s.rc.Done()
+}
+
+// Err returns any error from running the process, once it has exited. While the process is still
+// running, Err returns nil.
+func (s *OutputStream) Err() error {
+	select {
+	case <-s.rc.done:
+		return s.rc.err
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,14 @@
+package bsh
+
+// SymlinkForce creates a symlink at linkPath that points to target, replacing any symlink that
+// is already at linkPath. On Unix the new link is created at a temporary name in the same folder
+// and then renamed over linkPath, so anything reading the link never sees it missing mid-swap.
+// On Windows there is no atomic replacement, so any existing link is removed and then recreated.
+// If linkPath exists but is not a symlink, it is left alone, and an error is handled by this instance
+// of Bsh.
+func (b *Bsh) SymlinkForce(target, linkPath string) {
+	b.Verbosef("SymlinkForce: %s => %s", linkPath, target)
+	if err := replaceSymlink(target, linkPath); err != nil {
+		b.Panic(err)
+	}
+}
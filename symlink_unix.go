@@ -0,0 +1,30 @@
+//go:build !windows
+// +build !windows
+
+package bsh
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func replaceSymlink(target, linkPath string) error {
+	// rename would silently replace a file (or an empty folder), so only replace existing symlinks
+	if fi, err := os.Lstat(linkPath); err == nil && fi.Mode()&os.ModeSymlink == 0 {
+		return fmt.Errorf("%s already exists and is not a symlink", linkPath)
+	}
+	tmp := filepath.Join(
+		filepath.Dir(linkPath),
+		fmt.Sprintf(".%s.%d.tmp", filepath.Base(linkPath), time.Now().UnixNano()),
+	)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, linkPath); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package bsh
+
+import (
+	"fmt"
+	"os"
+)
+
+// Windows can't rename a symlink over an existing one, so this falls back to remove-then-create,
+// which leaves a brief window where linkPath doesn't exist.
+func replaceSymlink(target, linkPath string) error {
+	if fi, err := os.Lstat(linkPath); err == nil {
+		if fi.Mode()&os.ModeSymlink == 0 {
+			return fmt.Errorf("%s already exists and is not a symlink", linkPath)
+		}
+		if err := os.Remove(linkPath); err != nil {
+			return err
+		}
+	}
+	return os.Symlink(target, linkPath)
+}
@@ -0,0 +1,295 @@
+package bsh
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func (b *Bsh) TarGzFile(source, target string) {
+	b.Verbosef("TarGzFile: %s to %s", source, target)
+	if err := tarGzFile(source, target); err != nil {
+		b.Panic(err)
+	}
+}
+
+// TarGzFolder creates a gzipped tar at target that contains everything inside of source.
+// Symlinks are stored as symlinks (rather than being followed).
+func (b *Bsh) TarGzFolder(source, target string) {
+	b.Verbosef("TarGzFolder: %s to %s", source, target)
+	if err := tarGzFolder(source, target); err != nil {
+		b.Panic(err)
+	}
+}
+
+// UntarGz extracts every entry in the gzipped tar at source into destFolder, creating any needed
+// folders, recreating symlinks, and restoring the file modes stored in the tar. Entries (or
+// symlink targets) that would land outside of destFolder are rejected, including ones that would
+// only get there by going through symlinks.
+func (b *Bsh) UntarGz(source, destFolder string) {
+	b.Verbosef("UntarGz: %s to %s", source, destFolder)
+	if err := untarGz(source, destFolder); err != nil {
+		b.Panic(err)
+	}
+}
+
+// tarGzWriter wraps a tar.Writer that writes through a gzip.Writer into a file
+type tarGzWriter struct {
+	f  *os.File
+	gz *gzip.Writer
+	*tar.Writer
+}
+
+func createTarGz(target string) (*tarGzWriter, error) {
+	f, err := os.Create(target)
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(f)
+	return &tarGzWriter{f: f, gz: gz, Writer: tar.NewWriter(gz)}, nil
+}
+
+// add writes an entry named name for the file at path, which may be a file, folder, or symlink
+func (tw *tarGzWriter) add(path, name string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	var link string
+	if info.Mode()&os.ModeSymlink != 0 {
+		if link, err = os.Readlink(path); err != nil {
+			return err
+		}
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if info.IsDir() {
+		header.Name += "/"
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	return copyFileTo(tw, path)
+}
+
+// Close flushes and closes the tar writer, gzip writer, and file, in that order
+func (tw *tarGzWriter) Close() error {
+	errTar := tw.Writer.Close()
+	errGz := tw.gz.Close()
+	errFile := tw.f.Close()
+	for _, err := range []error{errTar, errGz, errFile} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tarGzFile(source, target string) error {
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", source)
+	}
+
+	tw, err := createTarGz(target)
+	if err != nil {
+		return err
+	}
+	if err := tw.add(source, filepath.Base(source)); err != nil {
+		tw.Close()
+		return err
+	}
+	return tw.Close()
+}
+
+func tarGzFolder(source, target string) error {
+	files, err := listFolder(source)
+	if err != nil {
+		return err
+	}
+
+	tw, err := createTarGz(target)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := tw.add(filepath.Join(source, file), file); err != nil {
+			tw.Close()
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func untarGz(source, destFolder string) error {
+	f, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	// symlinks extracted earlier (or already in destFolder) are followed when checking each entry,
+	// so the checks are against the real path of destFolder
+	root, err := filepath.Abs(destFolder)
+	if err != nil {
+		return err
+	}
+	if root, err = resolveExisting(root); err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := extractPath(destFolder, header.Name)
+		if err != nil {
+			return err
+		}
+		if err := checkResolvedWithin(root, target, header.Typeflag == tar.TypeSymlink); err != nil {
+			return fmt.Errorf("tar entry %s: %w", header.Name, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, os.ModePerm); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := untarSymlink(root, target, header.Linkname); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := extractFile(tr, target, header.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported type %q for tar entry %s", header.Typeflag, header.Name)
+		}
+	}
+}
+
+// checkResolvedWithin returns an error if target (or just its parent folder, if target is to be
+// replaced by a symlink) is outside of root once symlinks that already exist on disk are resolved.
+func checkResolvedWithin(root, target string, parentOnly bool) error {
+	path, err := filepath.Abs(target)
+	if err != nil {
+		return err
+	}
+	if parentOnly {
+		path = filepath.Dir(path)
+	}
+	if path, err = resolveExisting(path); err != nil {
+		return err
+	}
+	if !isWithin(root, path) {
+		return fmt.Errorf("%s resolves to %s, which is outside of %s", target, path, root)
+	}
+	return nil
+}
+
+// resolveExisting returns the absolute path with every symlink in it resolved, like
+// filepath.EvalSymlinks, except that parts of path that don't exist yet (including the targets of
+// dangling symlinks) are kept as-is instead of being an error. Each ".." is applied after the part
+// before it is resolved, just as the OS would.
+func resolveExisting(path string) (string, error) {
+	const sep = string(filepath.Separator)
+	vol := filepath.VolumeName(path)
+	cur, rest := vol+sep, path[len(vol):]
+	links := 0
+	for rest != "" {
+		name := rest
+		if i := strings.Index(rest, sep); i >= 0 {
+			name, rest = rest[:i], rest[i+1:]
+		} else {
+			rest = ""
+		}
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			cur = filepath.Dir(cur)
+			continue
+		}
+
+		next := filepath.Join(cur, name)
+		fi, err := os.Lstat(next)
+		if os.IsNotExist(err) || (err == nil && fi.Mode()&os.ModeSymlink == 0) {
+			cur = next
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		links++
+		if links > 255 {
+			return "", fmt.Errorf("too many levels of symlinks in %s", path)
+		}
+		link, err := os.Readlink(next)
+		if err != nil {
+			return "", err
+		}
+		link = filepath.FromSlash(link)
+		if filepath.IsAbs(link) {
+			vol = filepath.VolumeName(link)
+			cur, link = vol+sep, link[len(vol):]
+		}
+		rest = link + sep + rest
+	}
+	return cur, nil
+}
+
+// untarSymlink creates a symlink at target that points to link, replacing any existing file. Links
+// that point outside of root (once any symlinks they go through are resolved) are rejected, so
+// later entries can't be written through them.
+func untarSymlink(root, target, link string) error {
+	dir, err := filepath.Abs(filepath.Dir(target))
+	if err != nil {
+		return err
+	}
+	resolved := link
+	if !filepath.IsAbs(link) {
+		// not filepath.Join, which would apply any ".." in link before dir's symlinks are resolved
+		resolved = dir + string(filepath.Separator) + link
+	}
+	if resolved, err = resolveExisting(resolved); err != nil {
+		return err
+	}
+	if !isWithin(root, resolved) {
+		return fmt.Errorf("symlink %s => %s points outside of %s", target, link, root)
+	}
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Symlink(link, target)
+}
@@ -0,0 +1,171 @@
+package bsh
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+)
+
+// TaskGraph is a small task runner, where each named task can depend on other tasks.
+// Create one via Bsh.TaskGraph, register tasks via Add, then call Run.
+type TaskGraph struct {
+	// MaxParallel is the max number of tasks that can run at once (defaults to the number of CPUs).
+	MaxParallel int
+
+	b     *Bsh
+	tasks map[string]*graphTask
+}
+
+type graphTask struct {
+	name string
+	deps []string
+	fn   func()
+}
+
+// TaskGraph returns a new, empty TaskGraph.
+func (b *Bsh) TaskGraph() *TaskGraph {
+	return &TaskGraph{
+		MaxParallel: runtime.NumCPU(),
+		b:           b,
+		tasks:       make(map[string]*graphTask),
+	}
+}
+
+// Add registers a task, which will only run after all the tasks named in deps have finished.
+func (g *TaskGraph) Add(name string, deps []string, fn func()) *TaskGraph {
+	g.tasks[name] = &graphTask{name: name, deps: deps, fn: fn}
+	return g
+}
+
+// Run runs target, after first running all of its dependencies (and their dependencies, etc).
+// Tasks that don't depend on each other are run in parallel. If a task panics, no new tasks are
+// started, and once any running tasks finish, the error is handled by this instance of Bsh.
+func (g *TaskGraph) Run(target string) {
+	needed, err := g.collect(target)
+	if err != nil {
+		g.b.Panic(err)
+		return
+	}
+
+	waitingOn := make(map[string]int, len(needed))
+	dependents := make(map[string][]string, len(needed))
+	ready := make([]string, 0, len(needed))
+	for _, name := range needed {
+		t := g.tasks[name]
+		waitingOn[name] = len(t.deps)
+		for _, dep := range t.deps {
+			dependents[dep] = append(dependents[dep], name)
+		}
+		if len(t.deps) == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	maxParallel := g.MaxParallel
+	if maxParallel < 1 {
+		maxParallel = 1
+	}
+
+	type taskResult struct {
+		name string
+		err  error
+	}
+	results := make(chan taskResult)
+	running := 0
+	var firstErr error
+	for {
+		for len(ready) > 0 && running < maxParallel && firstErr == nil {
+			t := g.tasks[ready[0]]
+			ready = ready[1:]
+			running++
+			g.b.Verbosef("Task: %s", t.name)
+			go func() {
+				results <- taskResult{t.name, runTask(t.fn)}
+			}()
+		}
+		if running == 0 {
+			break
+		}
+		r := <-results
+		running--
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("task %s failed: %w", r.name, r.err)
+			}
+			continue
+		}
+		for _, d := range dependents[r.name] {
+			waitingOn[d]--
+			if waitingOn[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+
+	if firstErr != nil {
+		g.b.Panic(firstErr)
+	}
+}
+
+// collect returns the names of target and everything it depends on, in sorted order, or an
+// error if a task is missing or there's a cycle.
+func (g *TaskGraph) collect(target string) ([]string, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	var visit func(name string, from string) error
+	visit = func(name string, from string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("task %s has a circular dependency on %s", from, name)
+		case visited:
+			return nil
+		}
+		t, ok := g.tasks[name]
+		if !ok {
+			if len(from) == 0 {
+				return fmt.Errorf("unknown task %s", name)
+			}
+			return fmt.Errorf("task %s depends on unknown task %s", from, name)
+		}
+		state[name] = visiting
+		for _, dep := range t.deps {
+			if err := visit(dep, name); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	if err := visit(target, ""); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(state))
+	for name := range state {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// runTask calls fn, and returns any panic as an error. Like Try, runtime errors (which indicate a
+// bug) are not recovered.
+func runTask(fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			if e, ok := r.(error); ok {
+				err = e
+			} else {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+	fn()
+	return nil
+}
@@ -0,0 +1,71 @@
+package bsh
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestTaskGraph(t *testing.T) {
+	b := Bsh{}
+	var mu sync.Mutex
+	order := make([]string, 0, 4)
+	record := func(name string) func() {
+		return func() {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+		}
+	}
+
+	g := b.TaskGraph()
+	g.Add("build", []string{"gen", "deps"}, record("build"))
+	g.Add("gen", []string{"deps"}, record("gen"))
+	g.Add("deps", nil, record("deps"))
+	g.Add("unrelated", nil, record("unrelated"))
+	g.Run("build")
+
+	expected := []string{"deps", "gen", "build"}
+	if fmt.Sprint(order) != fmt.Sprint(expected) {
+		t.Errorf("expected tasks to run in order %v, but got %v", expected, order)
+	}
+
+	err := b.Try(func() {
+		b.TaskGraph().Add("a", []string{"b"}, func() {}).Add("b", []string{"a"}, func() {}).Run("a")
+	})
+	if err == nil {
+		t.Error("expected an error for a circular dependency")
+	}
+
+	ran := false
+	err = b.Try(func() {
+		g := b.TaskGraph()
+		g.MaxParallel = 1
+		g.Add("fail", nil, func() { b.Panic(fmt.Errorf("oops")) })
+		g.Add("after", []string{"fail"}, func() { ran = true })
+		g.Run("after")
+	})
+	if err == nil {
+		t.Error("expected an error from a failing task")
+	}
+	if ran {
+		t.Error("expected a task that depends on a failed task to not run")
+	}
+}
+
+func TestRunTaskRuntimeError(t *testing.T) {
+	if err := runTask(func() { panic("not an error") }); err == nil || err.Error() != "not an error" {
+		t.Errorf(`expected a panic to be returned as an error, but got %v`, err)
+	}
+
+	defer func() {
+		if _, ok := recover().(runtime.Error); !ok {
+			t.Errorf("expected runTask to re-panic with the runtime error")
+		}
+	}()
+	runTask(func() {
+		var m map[string]int
+		m["boom"] = 1
+	})
+}
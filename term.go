@@ -0,0 +1,50 @@
+package bsh
+
+import (
+	"io"
+	"os"
+	"strconv"
+
+	"golang.org/x/term"
+)
+
+const defaultTerminalWidth = 80
+
+// TerminalWidth returns the width (in columns) of the terminal that Stdout is attached to.
+// If Stdout isn't a terminal, it falls back to the COLUMNS env var, and then to 80.
+func (b *Bsh) TerminalWidth() int {
+	if fd, ok := b.stdoutTerminalFd(); ok {
+		if w, _, err := term.GetSize(fd); err == nil && w > 0 {
+			return w
+		}
+	}
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return defaultTerminalWidth
+}
+
+// isTerminal returns true if Stdout is attached to a terminal
+func (b *Bsh) isTerminal() bool {
+	_, ok := b.stdoutTerminalFd()
+	return ok
+}
+
+// isTerminalReader returns true if r is a file attached to a terminal
+func isTerminalReader(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// stdoutTerminalFd returns the file descriptor of Stdout, if it is a terminal
+func (b *Bsh) stdoutTerminalFd() (int, bool) {
+	f, ok := b.ensureStdout().(*os.File)
+	if !ok {
+		return 0, false
+	}
+	fd := int(f.Fd())
+	if !term.IsTerminal(fd) {
+		return 0, false
+	}
+	return fd, true
+}
@@ -0,0 +1,165 @@
+package bsh
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// lineWriter buffers writes until a full line is available, then passes each line (without
+// its trailing newline) through fn before writing the result to w. If fn returns an empty
+// string, the line is dropped. Any trailing partial line is held until Flush is called.
+type lineWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	fn  func(line string) string
+	buf []byte
+}
+
+func newLineWriter(w io.Writer, fn func(line string) string) *lineWriter {
+	return &lineWriter{w: w, fn: fn}
+}
+
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	lw.buf = append(lw.buf, p...)
+	for {
+		i := bytes.IndexByte(lw.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(lw.buf[:i])
+		lw.buf = lw.buf[i+1:]
+		if err := lw.writeLine(line, true); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes out any buffered partial line (without adding a newline).
+func (lw *lineWriter) Flush() error {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	if len(lw.buf) == 0 {
+		return nil
+	}
+	line := string(lw.buf)
+	lw.buf = lw.buf[:0]
+	return lw.writeLine(line, false)
+}
+
+func (lw *lineWriter) writeLine(line string, newline bool) error {
+	out := lw.fn(line)
+	if len(out) == 0 {
+		return nil
+	}
+	if newline {
+		out += "\n"
+	}
+	_, err := io.WriteString(lw.w, out)
+	return err
+}
+
+// ringBuffer is an io.Writer that only retains the last max bytes written to it.
+type ringBuffer struct {
+	mu   sync.Mutex
+	max  int
+	data []byte
+}
+
+func newRingBuffer(max int) *ringBuffer {
+	if max < 0 {
+		max = 0
+	}
+	return &ringBuffer{max: max, data: make([]byte, 0, max)}
+}
+
+func (rb *ringBuffer) Write(p []byte) (int, error) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	n := len(p)
+	if n >= rb.max {
+		rb.data = append(rb.data[:0], p[n-rb.max:]...)
+		return n, nil
+	}
+	if overflow := len(rb.data) + n - rb.max; overflow > 0 {
+		rb.data = append(rb.data[:0], rb.data[overflow:]...)
+	}
+	rb.data = append(rb.data, p...)
+	return n, nil
+}
+
+func (rb *ringBuffer) Len() int {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	return len(rb.data)
+}
+
+func (rb *ringBuffer) String() string {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	return string(rb.data)
+}
+
+func (rb *ringBuffer) Reset() {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.data = rb.data[:0]
+}
+
+// teeWriter returns a writer that writes to both w and extra, or just extra if w is nil.
+func teeWriter(w io.Writer, extra io.Writer) io.Writer {
+	if w == nil {
+		return extra
+	}
+	return io.MultiWriter(w, extra)
+}
+
+// syncWriter serializes writes to w, so it can be shared by writers used from different goroutines.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (sw *syncWriter) Write(p []byte) (int, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.Write(p)
+}
+
+// sameWriter reports whether a and b are the same writer. Like os/exec, it treats writers whose
+// dynamic types can't be compared as different.
+func sameWriter(a, b io.Writer) (same bool) {
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return a == b
+}
+
+// limitedBuffer is an io.Writer that retains only the first max bytes written to it, and
+// silently discards the rest.
+type limitedBuffer struct {
+	mu        sync.Mutex
+	max       int
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (lb *limitedBuffer) Write(p []byte) (int, error) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	remaining := lb.max - lb.buf.Len()
+	if remaining < len(p) {
+		lb.truncated = true
+		if remaining > 0 {
+			lb.buf.Write(p[:remaining])
+		}
+		return len(p), nil
+	}
+	lb.buf.Write(p)
+	return len(p), nil
+}
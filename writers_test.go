@@ -0,0 +1,43 @@
+package bsh
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLineWriter(t *testing.T) {
+	var sb strings.Builder
+	lw := newLineWriter(&sb, func(line string) string {
+		if line == "drop" {
+			return ""
+		}
+		return "> " + line
+	})
+	lw.Write([]byte("first\nsec"))
+	lw.Write([]byte("ond\ndrop\nthi"))
+	lw.Write([]byte("rd"))
+	lw.Flush()
+
+	actual := sb.String()
+	expected := "> first\n> second\n> third"
+	if actual != expected {
+		t.Errorf(`expected: "%s", but got "%s"`, expected, actual)
+	}
+}
+
+func TestRingBuffer(t *testing.T) {
+	rb := newRingBuffer(8)
+	rb.Write([]byte("abc"))
+	rb.Write([]byte("defgh"))
+	if actual := rb.String(); actual != "abcdefgh" {
+		t.Errorf(`expected: "abcdefgh", but got "%s"`, actual)
+	}
+	rb.Write([]byte("ijk"))
+	if actual := rb.String(); actual != "defghijk" {
+		t.Errorf(`expected: "defghijk", but got "%s"`, actual)
+	}
+	rb.Write([]byte("0123456789"))
+	if actual := rb.String(); actual != "23456789" {
+		t.Errorf(`expected: "23456789", but got "%s"`, actual)
+	}
+}
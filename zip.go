@@ -6,7 +6,11 @@ import (
 	"io"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
 )
 
 func (b *Bsh) ZipFile(source, target string) {
@@ -30,6 +34,51 @@ func (b *Bsh) ZipFolder(source, target string) {
 	}
 }
 
+// ZipFolderReproducible is like ZipFolder, but produces byte-identical output given identical
+// input, regardless of file timestamps or OS. Entries are sorted by name, every entry's modified
+// time is set to SOURCE_DATE_EPOCH (if set) or else 1980-01-01, and file modes are normalized
+// to 0755 for folders and executables, and 0644 for everything else.
+func (b *Bsh) ZipFolderReproducible(source, target string) {
+	b.Verbosef("ZipFolderReproducible: %s to %s", source, target)
+	modified, err := sourceDateEpoch()
+	if err != nil {
+		b.Panic(err)
+	}
+	if err := zipFolderReproducible(source, target, modified); err != nil {
+		b.Panic(err)
+	}
+}
+
+// ZipFromMap creates a zip at target that contains a file for each entry in files, where the key
+// is the slash-separated path within the zip, and the value is the file's contents. Folder
+// entries are added for any parent folders. Entries are sorted, and use the same modified time
+// as ZipFolderReproducible, so the output is stable.
+func (b *Bsh) ZipFromMap(target string, files map[string][]byte) {
+	b.Verbosef("ZipFromMap: %d file(s) to %s", len(files), target)
+	modified, err := sourceDateEpoch()
+	if err != nil {
+		b.Panic(err)
+	}
+	if err := zipFromMap(target, files, modified); err != nil {
+		b.Panic(err)
+	}
+}
+
+// Unzip extracts every entry in the zip at source into destFolder, creating any needed folders,
+// and restoring the file modes stored in the zip. Entries whose paths would land outside of
+// destFolder (eg "../../etc/passwd") are rejected.
+func (b *Bsh) Unzip(source, destFolder string) {
+	if err := b.UnzipErr(source, destFolder); err != nil {
+		b.Panic(err)
+	}
+}
+
+// UnzipErr is Unzip, but the error is returned instead of being handled by this instance of Bsh.
+func (b *Bsh) UnzipErr(source, destFolder string) error {
+	b.Verbosef("Unzip: %s to %s", source, destFolder)
+	return unzip(source, destFolder)
+}
+
 func zipFile(source, target string, mode *fs.FileMode) error {
 	fzip, err := os.Create(target)
 	if err != nil {
@@ -74,7 +123,8 @@ func zipFile(source, target string, mode *fs.FileMode) error {
 	return err
 }
 
-func zipFolder(source, target string) error {
+// listFolder returns the slash-separated relative paths of everything inside source
+func listFolder(source string) ([]string, error) {
 	files := make([]string, 0, 256)
 
 	err := fs.WalkDir(os.DirFS(source), ".", func(path string, d fs.DirEntry, err error) error {
@@ -87,6 +137,14 @@ func zipFolder(source, target string) error {
 		files = append(files, path)
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func zipFolder(source, target string) error {
+	files, err := listFolder(source)
 	if err != nil {
 		return err
 	}
@@ -142,3 +200,209 @@ func zipFolder(source, target string) error {
 
 	return nil
 }
+
+// sourceDateEpoch returns the time specified by the SOURCE_DATE_EPOCH env var (see
+// https://reproducible-builds.org/specs/source-date-epoch/), or 1980-01-01 if it isn't set.
+func sourceDateEpoch() (time.Time, error) {
+	str, ok := os.LookupEnv("SOURCE_DATE_EPOCH")
+	if !ok || len(str) == 0 {
+		return time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	secs, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: %w", str, err)
+	}
+	return time.Unix(secs, 0).UTC(), nil
+}
+
+func zipFolderReproducible(source, target string, modified time.Time) error {
+	files, err := listFolder(source)
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	fzip, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer fzip.Close()
+
+	zw := zip.NewWriter(fzip)
+	defer zw.Close()
+
+	for _, file := range files {
+		path := filepath.Join(source, file)
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		header := &zip.FileHeader{
+			Name:     file,
+			Method:   zip.Deflate,
+			Modified: modified,
+		}
+		switch {
+		case info.IsDir():
+			header.Name += "/"
+			header.Method = zip.Store
+			header.SetMode(fs.ModeDir | 0755)
+		case info.Mode()&0111 != 0:
+			header.SetMode(0755)
+		default:
+			header.SetMode(0644)
+		}
+
+		hw, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			continue
+		}
+
+		if err := copyFileTo(hw, path); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// copyFileTo copies the contents of the file at path into w
+func copyFileTo(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func zipFromMap(target string, files map[string][]byte, modified time.Time) error {
+	dirs := make(map[string]bool)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+		for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			dirs[dir+"/"] = true
+		}
+	}
+	for dir := range dirs {
+		if _, exists := files[dir]; !exists {
+			names = append(names, dir)
+		}
+	}
+	sort.Strings(names)
+
+	fzip, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer fzip.Close()
+
+	zw := zip.NewWriter(fzip)
+	defer zw.Close()
+
+	for _, name := range names {
+		header := &zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: modified,
+		}
+		if dirs[name] {
+			header.Method = zip.Store
+			header.SetMode(fs.ModeDir | 0755)
+		} else {
+			header.SetMode(0644)
+		}
+
+		hw, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if dirs[name] {
+			continue
+		}
+		if _, err := hw.Write(files[name]); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// extractPath returns the path that the archive entry name should be extracted to, or an error if
+// that path is outside of destFolder.
+func extractPath(destFolder, name string) (string, error) {
+	dest := filepath.Clean(destFolder)
+	target := filepath.Join(dest, filepath.FromSlash(name))
+	if !isWithin(dest, target) {
+		return "", fmt.Errorf("archive entry %s would be extracted outside of %s", name, destFolder)
+	}
+	return target, nil
+}
+
+func unzip(source, destFolder string) error {
+	zr, err := zip.OpenReader(source)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		target, err := extractPath(destFolder, f.Name)
+		if err != nil {
+			return err
+		}
+
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, os.ModePerm); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return err
+		}
+		if err := unzipFile(f, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unzipFile(f *zip.File, target string) error {
+	perm := f.Mode().Perm()
+	if perm == 0 {
+		perm = 0644
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return extractFile(rc, target, perm)
+}
+
+// extractFile creates/overwrites the file at target with the contents of r, and sets its mode to perm
+func extractFile(r io.Reader, target string, perm os.FileMode) error {
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	// OpenFile only applies perm to new files, so make sure an existing file gets it as well
+	return os.Chmod(target, perm)
+}
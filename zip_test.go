@@ -1,8 +1,14 @@
 package bsh
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
+	"time"
 )
 
 func ensureLocalFolder(t *testing.T) {
@@ -23,7 +29,11 @@ func TestZipFile(t *testing.T) {
 		if !b.Exists("zip_test.zip") {
 			t.Fatal("ZipExe did not produce output")
 		}
-		// TODO: when unzip is added, use that to test the zip contents here
+		b.RemoveAll("zip_test_out")
+		b.Unzip("zip_test.zip", "zip_test_out")
+		if !b.IsFile("zip_test_out/zip_test.txt") {
+			t.Fatal("Unzip did not extract zip_test.txt")
+		}
 	})
 }
 
@@ -31,14 +41,144 @@ func TestZipFolder(t *testing.T) {
 	ensureLocalFolder(t)
 	b := Bsh{}
 	b.InDir("local", func() {
+		b.RemoveAll("zip_folder")
 		b.RemoveAll("zip_test.zip")
-		b.Touch("zip_test.txt")
-		b.Touch("foo/test2")
-		b.Touch("foo/test3")
-		b.ZipFolder(".", "zip_test.zip")
+		b.Touch("zip_folder/zip_test.txt")
+		b.Touch("zip_folder/foo/test2")
+		b.Touch("zip_folder/foo/test3")
+		b.ZipFolder("zip_folder", "zip_test.zip")
 		if !b.Exists("zip_test.zip") {
 			t.Fatal("ZipExe did not produce output")
 		}
-		// TODO: when unzip is added, use that to test the zip contents here
+		b.RemoveAll("zip_test_out")
+		b.Unzip("zip_test.zip", "zip_test_out")
+		for _, file := range []string{"zip_test.txt", "foo/test2", "foo/test3"} {
+			if !b.IsFile(filepath.Join("zip_test_out", file)) {
+				t.Errorf("Unzip did not extract %s", file)
+			}
+		}
+	})
+}
+
+func TestZipFolderReproducible(t *testing.T) {
+	ensureLocalFolder(t)
+	b := Bsh{}
+	b.InDir("local", func() {
+		b.RemoveAll("zip_repro")
+		b.MkdirAll("zip_repro/sub")
+		b.Write("zip_repro/a.txt", "alpha")
+		b.Write("zip_repro/sub/b.txt", "bravo")
+		b.ZipFolderReproducible("zip_repro", "zip_repro1.zip")
+
+		later := time.Now().Add(time.Hour)
+		b.Must(os.Chtimes("zip_repro/a.txt", later, later))
+		b.Must(os.Chtimes("zip_repro/sub/b.txt", later, later))
+		b.ZipFolderReproducible("zip_repro", "zip_repro2.zip")
+
+		if !bytes.Equal(b.ReadFile("zip_repro1.zip"), b.ReadFile("zip_repro2.zip")) {
+			t.Fatal("ZipFolderReproducible output changed when only file times changed")
+		}
 	})
 }
+
+func TestUnzipRejectsZipSlip(t *testing.T) {
+	ensureLocalFolder(t)
+	b := Bsh{}
+	b.InDir("local", func() {
+		b.ZipFromMap("zip_slip.zip", map[string][]byte{"../zip_slip.txt": []byte("escaped")})
+		b.RemoveAll("zip_slip_out")
+		if err := b.UnzipErr("zip_slip.zip", "zip_slip_out"); err == nil {
+			t.Fatal("expected Unzip to reject an entry outside of the destination")
+		}
+		if b.Exists("zip_slip.txt") {
+			t.Fatal("Unzip wrote a file outside of the destination")
+		}
+	})
+}
+
+func TestTarGzFolder(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symlinks requires extra privileges on windows")
+	}
+	ensureLocalFolder(t)
+	b := Bsh{}
+	b.InDir("local", func() {
+		b.RemoveAll("targz_test")
+		b.MkdirAll("targz_test/sub")
+		b.Write("targz_test/sub/a.txt", "alpha")
+		b.Must(os.Chmod("targz_test/sub/a.txt", 0750))
+		b.Must(os.Symlink("sub/a.txt", "targz_test/link"))
+		b.TarGzFolder("targz_test", "targz_test.tar.gz")
+
+		b.RemoveAll("targz_test_out")
+		b.UntarGz("targz_test.tar.gz", "targz_test_out")
+		if actual := b.Read("targz_test_out/sub/a.txt"); actual != "alpha" {
+			t.Errorf(`expected "alpha", but got "%s"`, actual)
+		}
+		if info := b.Stat("targz_test_out/sub/a.txt"); info.Mode().Perm() != 0750 {
+			t.Errorf("expected mode 0750, but got %o", info.Mode().Perm())
+		}
+		link, err := os.Readlink("targz_test_out/link")
+		if err != nil || link != "sub/a.txt" {
+			t.Errorf(`expected symlink to "sub/a.txt", but got "%s" (%v)`, link, err)
+		}
+	})
+}
+
+func TestUntarGzRejectsSymlinkChains(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symlinks requires extra privileges on windows")
+	}
+	ensureLocalFolder(t)
+	b := Bsh{}
+	b.InDir("local", func() {
+		b.RemoveAll("targz_slip")
+		b.MkdirAll("targz_slip")
+
+		// each link looks like it stays inside the destination, but b resolves to its parent
+		writeTestTarGz(t, "targz_slip/chain.tar.gz", []tar.Header{
+			{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: "b", Typeflag: tar.TypeSymlink, Linkname: "a/.."},
+			{Name: "b/escaped.txt", Typeflag: tar.TypeReg, Mode: 0644},
+		})
+		// the same, but through a link to a folder that doesn't exist yet
+		writeTestTarGz(t, "targz_slip/dangling.tar.gz", []tar.Header{
+			{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: "c", Typeflag: tar.TypeSymlink, Linkname: "a/../made"},
+			{Name: "c/escaped.txt", Typeflag: tar.TypeReg, Mode: 0644},
+		})
+
+		for _, archive := range []string{"chain.tar.gz", "dangling.tar.gz"} {
+			b.RemoveAll("targz_slip/out")
+			err := b.Try(func() { b.UntarGz("targz_slip/"+archive, "targz_slip/out") })
+			if err == nil {
+				t.Errorf("expected UntarGz to reject %s", archive)
+			}
+			if b.Exists("targz_slip/escaped.txt") || b.Exists("targz_slip/made") {
+				t.Fatalf("UntarGz of %s wrote outside of the destination", archive)
+			}
+		}
+	})
+}
+
+func writeTestTarGz(t *testing.T, path string, headers []tar.Header) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for i := range headers {
+		if err := tw.WriteHeader(&headers[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}